@@ -15,27 +15,186 @@
 package hbird
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
 	"github.com/spf13/cobra"
 	"github.com/winc-link/hummingbird-cli/config"
+	"github.com/winc-link/hummingbird-cli/internal/errs"
 	"github.com/winc-link/hummingbird-cli/internal/install"
+	"github.com/winc-link/hummingbird-cli/internal/layout"
 	"github.com/winc-link/hummingbird-cli/internal/new"
+	"github.com/winc-link/hummingbird-cli/internal/settings"
+	"github.com/winc-link/hummingbird-cli/internal/template"
+	"github.com/winc-link/hummingbird-cli/internal/upgrade"
+	"github.com/winc-link/hummingbird-cli/utility"
 )
 
 var CmdRoot = &cobra.Command{
 	Use:     "hb",
 	Example: "hb new demo-driver",
 	Short:   config.LogoContent,
-	Version: config.LogoContent + "\n" + fmt.Sprintf("Hummingbird %s - Copyright (c) 2023 hb \nReleased under the MIT License.\n", config.Version),
+	Long: config.LogoContent + `
+
+Exit codes:
+  0    success
+  1    unexpected error
+  2    invalid usage, flags, answers or template
+  3    network failure, e.g. a failed git clone or download
+  4    missing toolchain, e.g. git or go not installed
+  5    filesystem permission denied
+  130  cancelled by the user`,
+	Version:       config.LogoContent + "\n" + fmt.Sprintf("Hummingbird %s - Copyright (c) 2023 hb \nReleased under the MIT License.\n", config.Version),
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		started = true
+		if output != "text" && output != "json" {
+			return errs.New(errs.Validation, "invalid --output %q, expect text or json", output)
+		}
+		if err := config.Init(cmd, !managesConfig(cmd)); err != nil {
+			return err
+		}
+		utility.Init(yes)
+		utility.SetColor(!noColor)
+		if err := utility.SetFormat(logFormat); err != nil {
+			return errs.Wrap(errs.Validation, err)
+		}
+		if !cmd.Flags().Changed("log-level") {
+			switch {
+			case debug:
+				logLevel = "debug"
+			case quiet:
+				logLevel = "warn"
+			}
+		}
+		level, err := utility.ParseLevel(logLevel)
+		if err != nil {
+			return errs.Wrap(errs.Validation, err)
+		}
+		utility.SetLevel(level)
+		if logFile != "" {
+			if err = utility.SetLogFile(logFile); err != nil {
+				return err
+			}
+		}
+		utility.Debugf("hb %s %s", config.Version, strings.Join(os.Args[1:], " "))
+
+		c := config.Current()
+		if c.Profile != "" {
+			utility.Debugf("profile %s", c.Profile)
+		}
+		if proxy := c.Get("proxy"); proxy != "" {
+			setProxy(proxy)
+		}
+		layout.SetUserRegistryFile(c.Get("templates"))
+		if probe, _ := strconv.ParseBool(c.Get("probe")); !probe {
+			layout.SetProber(nil)
+		}
+		layout.SetRewrites(c.Rewrites())
+		layout.SetTokens(c.Tokens())
+		layout.SetSSHKey(c.Get("ssh-key"))
+		return nil
+	},
 }
 
+var (
+	output  = "text"
+	yes     bool
+	debug   bool
+	noColor bool
+	quiet   bool
+
+	logLevel  = "info"
+	logFormat = utility.FormatText
+	logFile   string
+	profile   string
+	// started tells errors of a command from the usage errors reported by
+	// cobra before running it.
+	started bool
+)
+
 func init() {
+	CmdRoot.PersistentFlags().StringVar(&output, "output", output, "format of the errors, text or json")
+	CmdRoot.PersistentFlags().BoolVarP(&yes, "yes", "y", yes, "answer every question with its default, env "+utility.EnvName)
+	CmdRoot.PersistentFlags().BoolVar(&debug, "debug", debug, "show debug output, env "+utility.DebugEnv)
+	CmdRoot.PersistentFlags().BoolVar(&noColor, "no-color", noColor, "disable colored output, env "+utility.NoColorEnv+" or NO_COLOR")
+	CmdRoot.PersistentFlags().BoolVarP(&quiet, "quiet", "q", quiet, "only show errors and prompts, env "+utility.QuietEnv)
+	CmdRoot.PersistentFlags().StringVar(&logLevel, "log-level", logLevel, "lowest level logged, trace, debug, info, warn or error, env "+utility.LevelEnv)
+	CmdRoot.PersistentFlags().StringVar(&logFormat, "log-format", logFormat, "format of the logs, text or json, env "+utility.FormatEnv)
+	CmdRoot.PersistentFlags().StringVar(&profile, "profile", profile, "configuration profile to use, env "+config.ProfileEnv)
+	CmdRoot.PersistentFlags().StringVar(&logFile, "log-file", logFile, "also append every log, trace level included, to this file, env "+utility.FileEnv)
+
 	CmdRoot.AddCommand(new.CmdNew)
 	CmdRoot.AddCommand(install.CmdInstall)
+	CmdRoot.AddCommand(template.CmdTemplate)
+	CmdRoot.AddCommand(upgrade.CmdUpgradeProject)
+	CmdRoot.AddCommand(settings.CmdConfig)
+	CmdRoot.AddCommand(settings.CmdProfile)
+
+}
 
+// managesConfig reports whether `cmd` is one of the config or profile
+// commands, which must work with a broken configuration to repair it.
+func managesConfig(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c == settings.CmdConfig || c == settings.CmdProfile {
+			return true
+		}
+	}
+	return false
 }
 
-// Execute executes the root command.
+// Execute executes the root command and reports its error, if any. The
+// commands run by hb are killed on SIGINT or SIGTERM; a second signal
+// terminates hb right away.
 func Execute() error {
-	return CmdRoot.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	defer utility.CloseLog()
+	cmd, err := CmdRoot.ExecuteContextC(ctx)
+	if err == nil {
+		return nil
+	}
+	if !started {
+		err = errs.Wrap(errs.Validation, err)
+	}
+	if errs.IsReported(err) {
+		return err
+	}
+	kind := errs.KindOf(err)
+	if output == "json" {
+		envelope := map[string]interface{}{
+			"error": map[string]interface{}{
+				"kind":    kind.String(),
+				"code":    kind.ExitCode(),
+				"message": utility.Redact(err.Error()),
+			},
+		}
+		data, _ := json.Marshal(envelope)
+		fmt.Println(string(data))
+		return err
+	}
+	utility.Error(err)
+	if !started {
+		fmt.Fprintf(os.Stderr, "Run '%s --help' for usage.\n", cmd.CommandPath())
+	}
+	return err
+}
+
+// setProxy makes git and the downloads of hb go through `proxy`.
+func setProxy(proxy string) {
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+		_ = os.Setenv(name, proxy)
+	}
 }
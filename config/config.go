@@ -21,14 +21,7 @@ var LogoContent = "\n ____  ____                                     _
 var (
 	Version = "1.0"
 
-	GitHubAddr              = "https://github.com/"
-	GiteeAddr               = "https://gitee.com/"
-	TcpProtocolDriver       = "winc-link/hummingbird-tcp-driver"
-	UdpProtocolDriver       = "winc-link/hummingbird-udp-driver"
-	CoapProtocolDriver      = "winc-link/hummingbird-coap-driver"
-	MqttProtocolDriver      = "winc-link/hummingbird-mqtt-driver"
-	HttpProtocolDriver      = "winc-link/hummingbird-http-driver"
-	WebSocketProtocolDriver = "winc-link/hummingbird-websocket-driver"
-	ModbusProtocolDriver    = "winc-link/hummingbird-modbus-driver"
-	OpcuaProtocolDriver     = "winc-link/hummingbird-opcua-driver"
+	// ModulePrefixEnv names the environment variable holding the default module
+	// path prefix of new projects, e.g. git.company.com/iot/drivers.
+	ModulePrefixEnv = "HB_MODULE_PREFIX"
 )
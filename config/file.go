@@ -0,0 +1,573 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+	"github.com/winc-link/hummingbird-cli/utility"
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFile is the project level configuration, relative to the working directory.
+const ProjectFile = ".hb/config.yaml"
+
+// Key is a configuration key. It is read from the configuration files, from
+// its environment variables and from the flag of the same name.
+type Key struct {
+	Name        string
+	Description string
+	Default     string
+	Bool        bool
+	// Env are the environment variables setting the key, first one wins.
+	Env []string
+	// Commands are the commands whose flag is set by the key, hb itself when
+	// empty, i.e. the key sets the global flag of the same name, if any.
+	Commands []string
+	// Check validates a value, if set.
+	Check func(value string) error
+}
+
+// Keys are the configuration keys hb knows about.
+var Keys = []*Key{
+	{Name: "registry", Description: "registry templates are fetched from, e.g. Github or Gitee", Env: []string{"HB_REGISTRY"}, Commands: []string{"hb new", "hb template validate"}},
+	{Name: "protocol", Description: "template or protocol of hb new", Env: []string{"HB_PROTOCOL"}, Commands: []string{"hb new"}},
+	{Name: "module-prefix", Description: "organization prefix of the default module path", Env: []string{ModulePrefixEnv}, Commands: []string{"hb new"}},
+	{Name: "install-dir", Description: "folder hb install copies the binary to", Env: []string{"HB_INSTALL_DIR"}, Commands: []string{"hb install"}},
+	{Name: "yes", Description: "answer every question with its default", Bool: true, Default: "false", Env: []string{utility.EnvName}},
+	{Name: "debug", Description: "show debug output", Bool: true, Default: "false", Env: []string{utility.DebugEnv}},
+	{Name: "no-color", Description: "disable colored output", Bool: true, Default: "false", Env: []string{utility.NoColorEnv, "NO_COLOR"}},
+	{Name: "quiet", Description: "only show errors and prompts", Bool: true, Default: "false", Env: []string{utility.QuietEnv}},
+	{Name: "log-level", Description: "lowest level logged", Default: "info", Env: []string{utility.LevelEnv}, Check: func(v string) error {
+		_, err := utility.ParseLevel(v)
+		return err
+	}},
+	{Name: "log-format", Description: "format of the logs, text or json", Default: utility.FormatText, Env: []string{utility.FormatEnv}, Check: func(v string) error {
+		if v != utility.FormatText && v != utility.FormatJSON {
+			return fmt.Errorf("unknown log format %q, expect text or json", v)
+		}
+		return nil
+	}},
+	{Name: "log-file", Description: "file every log is appended to", Env: []string{utility.FileEnv}},
+	{Name: "profile", Description: "active profile, see hb profile list", Env: []string{ProfileEnv}},
+	{Name: "templates", Description: "template registry file used instead of ~/.hb/templates.yaml", Env: []string{"HB_TEMPLATES"}},
+	{Name: "proxy", Description: "HTTP(S) proxy of git and of the downloads", Env: []string{"HB_PROXY"}},
+	{Name: "platform", Description: "Hummingbird platform endpoint, given to the template hooks as HB_PLATFORM", Env: []string{"HB_PLATFORM"}},
+	{Name: "probe", Description: "check a remote answers before fetching from it, disable for ssh aliases or git proxies", Bool: true, Default: "true", Env: []string{"HB_PROBE"}},
+	{Name: "ssh-key", Description: "private key of the SSH template repositories", Env: []string{"HB_SSH_KEY"}},
+}
+
+// LookupKey returns the key named `name`, or nil.
+func LookupKey(name string) *Key {
+	for _, k := range Keys {
+		if k.Name == name {
+			return k
+		}
+	}
+	return nil
+}
+
+// Validate checks `value` is valid for the key, and returns it normalized.
+func (k *Key) Validate(value string) (string, error) {
+	if k.Bool {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", errs.New(errs.Validation, "invalid %s %q, expect true or false", k.Name, value)
+		}
+		return strconv.FormatBool(b), nil
+	}
+	if k.Check != nil {
+		if err := k.Check(value); err != nil {
+			return "", errs.Wrap(errs.Validation, err)
+		}
+	}
+	return value, nil
+}
+
+// appliesTo reports whether the key sets the flag of `cmd`.
+func (k *Key) appliesTo(cmd *cobra.Command) bool {
+	if len(k.Commands) == 0 {
+		return cmd.Root().PersistentFlags().Lookup(k.Name) != nil
+	}
+	for _, c := range k.Commands {
+		if c == cmd.CommandPath() {
+			return true
+		}
+	}
+	return false
+}
+
+// Setting is the value of a key and where it comes from: "default",
+// "file:<path>", "profile:<name>:<path>", "env:<name>" or "flag:--<name>".
+type Setting struct {
+	Key    string
+	Value  string
+	Origin string
+}
+
+// layer is one source of settings, in increasing precedence.
+type layer struct {
+	origin string
+	values map[string]string
+}
+
+// Config merges, from the lowest to the highest precedence, the defaults,
+// the system, user and project files, the active profile, the environment
+// variables and the flags.
+type Config struct {
+	layers   []layer
+	rewrites map[string]Setting
+	tokens   map[string]Setting
+	// Profile is the active profile, "" if none.
+	Profile string
+}
+
+var current = &Config{}
+
+// Current returns the configuration of the running command.
+func Current() *Config {
+	return current
+}
+
+// SystemFile returns the configuration file shared by every user.
+func SystemFile() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), "hb", "config.yaml")
+	}
+	return "/etc/hb/config.yaml"
+}
+
+// UserFile returns the configuration file of the user, e.g. ~/.config/hb/config.yaml.
+func UserFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "hb", "config.yaml"), nil
+}
+
+// Files returns the configuration files, from the lowest precedence.
+func Files() ([]string, error) {
+	user, err := UserFile()
+	if err != nil {
+		return nil, err
+	}
+	return []string{SystemFile(), user, ProjectFile}, nil
+}
+
+// Load reads every layer but the flags. The active profile is `profile`,
+// or else the one set by $HB_PROFILE or by the files. Unless `strict`, invalid
+// values and an unknown profile are warnings, so that they can be repaired.
+func Load(profile string, strict bool) (*Config, error) {
+	c := &Config{rewrites: map[string]Setting{}, tokens: map[string]Setting{}}
+	defaults := map[string]string{}
+	for _, k := range Keys {
+		if k.Default != "" {
+			defaults[k.Name] = k.Default
+		}
+	}
+	c.layers = append(c.layers, layer{origin: "default", values: defaults})
+
+	paths, err := Files()
+	if err != nil {
+		return nil, err
+	}
+	files := make([]*File, len(paths))
+	for i, path := range paths {
+		if files[i], err = ReadFile(path); err != nil {
+			return nil, err
+		}
+		layers, err := fileLayers("file:"+path, path, files[i].Values, strict)
+		if err != nil {
+			return nil, err
+		}
+		c.layers = append(c.layers, layers...)
+		for from, to := range files[i].Rewrites {
+			c.rewrites[from] = Setting{Key: from, Value: to, Origin: "file:" + path}
+		}
+		for host, token := range files[i].Tokens {
+			c.tokens[host] = Setting{Key: host, Value: token, Origin: "file:" + path}
+		}
+	}
+
+	env, err := envLayers(strict)
+	if err != nil {
+		return nil, err
+	}
+	if profile == "" {
+		profile = c.Get("profile")
+		for _, l := range env {
+			if v, ok := l.values["profile"]; ok {
+				profile = v
+			}
+		}
+	}
+	if profile != "" {
+		found := false
+		for i, path := range paths {
+			values, ok := files[i].Profiles[profile]
+			if !ok {
+				continue
+			}
+			found = true
+			if _, ok = values["profile"]; ok {
+				return nil, errs.New(errs.Validation, "%s: profile %s cannot set the active profile", path, profile)
+			}
+			layers, err := fileLayers("profile:"+profile+":"+path, path, values, strict)
+			if err != nil {
+				return nil, err
+			}
+			c.layers = append(c.layers, layers...)
+		}
+		switch {
+		case found:
+			c.Profile = profile
+		case strict:
+			return nil, errs.New(errs.Validation, "unknown profile %s, see `hb profile list`", profile)
+		default:
+			utility.Warnf("unknown profile %s, ignored", profile)
+		}
+	}
+	c.layers = append(c.layers, env...)
+	return c, nil
+}
+
+// fileLayers returns a layer of `origin` per key of `values` read from
+// `path`, warning about the unknown keys, and about the invalid values
+// unless `strict`.
+func fileLayers(origin, path string, values map[string]string, strict bool) ([]layer, error) {
+	var layers []layer
+	for _, name := range sortedKeys(values) {
+		k := LookupKey(name)
+		if k == nil {
+			utility.Warnf("unknown configuration key %s in %s, ignored", name, path)
+			continue
+		}
+		if _, err := k.Validate(values[name]); err != nil {
+			if strict {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			utility.Warnf("%s: %s", path, err)
+		}
+		layers = append(layers, layer{origin: origin, values: map[string]string{name: values[name]}})
+	}
+	return layers, nil
+}
+
+// envLayers returns the layers of the environment variables.
+func envLayers(strict bool) ([]layer, error) {
+	var layers []layer
+	for _, k := range Keys {
+		for _, env := range k.Env {
+			v := os.Getenv(env)
+			if v == "" {
+				continue
+			}
+			if env == "NO_COLOR" {
+				// Any value disables colors, see https://no-color.org.
+				v = "true"
+			}
+			if _, err := k.Validate(v); err != nil {
+				if strict {
+					return nil, fmt.Errorf("$%s: %w", env, err)
+				}
+				utility.Warnf("$%s: %s", env, err)
+			}
+			layers = append(layers, layer{origin: "env:" + env, values: map[string]string{k.Name: v}})
+			break
+		}
+	}
+	return layers, nil
+}
+
+// Init loads the configuration and sets the flags of `cmd` not given on the
+// command line from it. Unless `strict`, see Load, the invalid values are
+// listed but leave the flags alone.
+func Init(cmd *cobra.Command, strict bool) error {
+	flags := cmd.Flags()
+	profile := ""
+	if f := flags.Lookup("profile"); f != nil && f.Changed {
+		profile = f.Value.String()
+	}
+	c, err := Load(profile, strict)
+	if err != nil {
+		return err
+	}
+	given := map[string]string{}
+	for _, k := range Keys {
+		f := flags.Lookup(k.Name)
+		if f == nil || !k.appliesTo(cmd) {
+			continue
+		}
+		if f.Changed {
+			given[k.Name] = f.Value.String()
+			continue
+		}
+		if s, ok := c.Lookup(k.Name); ok && s.Origin != "default" {
+			if _, err = k.Validate(s.Value); err != nil {
+				continue
+			}
+			if err = flags.Set(k.Name, s.Value); err != nil {
+				return errs.New(errs.Validation, "set --%s from %s failed: %w", k.Name, s.Origin, err)
+			}
+			// Only the command line counts as given.
+			f.Changed = false
+		}
+	}
+	for name, v := range given {
+		c.layers = append(c.layers, layer{origin: "flag:--" + name, values: map[string]string{name: v}})
+	}
+	current = c
+	return nil
+}
+
+// Lookup returns the effective setting of `key`.
+func (c *Config) Lookup(key string) (Setting, bool) {
+	for i := len(c.layers) - 1; i >= 0; i-- {
+		if v, ok := c.layers[i].values[key]; ok {
+			return Setting{Key: key, Value: v, Origin: c.layers[i].origin}, true
+		}
+	}
+	return Setting{}, false
+}
+
+// Get returns the effective value of `key`, or "".
+func (c *Config) Get(key string) string {
+	s, _ := c.Lookup(key)
+	return s.Value
+}
+
+// Rewrites returns the URL rewrite rules of every file: a URL starting with a
+// key has this prefix replaced by its value.
+func (c *Config) Rewrites() map[string]string {
+	rules := make(map[string]string, len(c.rewrites))
+	for from, s := range c.rewrites {
+		rules[from] = s.Value
+	}
+	return rules
+}
+
+// RewriteSettings returns the URL rewrite rules with their origin, sorted by prefix.
+func (c *Config) RewriteSettings() []Setting {
+	list := make([]Setting, 0, len(c.rewrites))
+	for _, from := range sortedKeys(c.rewrites) {
+		list = append(list, c.rewrites[from])
+	}
+	return list
+}
+
+// Tokens returns the tokens of the HTTPS template repositories, by host.
+func (c *Config) Tokens() map[string]string {
+	byHost := make(map[string]string, len(c.tokens))
+	for host, s := range c.tokens {
+		byHost[host] = s.Value
+	}
+	return byHost
+}
+
+// TokenSettings returns the hosts having a token with their origin, sorted
+// by host. The tokens themselves are replaced by utility.Redacted.
+func (c *Config) TokenSettings() []Setting {
+	list := make([]Setting, 0, len(c.tokens))
+	for _, host := range sortedKeys(c.tokens) {
+		s := c.tokens[host]
+		s.Value = utility.Redacted
+		list = append(list, s)
+	}
+	return list
+}
+
+// Settings returns the effective setting of every key having a value, in
+// the order of Keys.
+func (c *Config) Settings() []Setting {
+	var list []Setting
+	for _, k := range Keys {
+		if s, ok := c.Lookup(k.Name); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// File is the content of a configuration file: keys, profiles, URL rewrite
+// rules and tokens by host, later files replacing the rules and tokens of
+// the same prefix or host.
+//
+//	registry: Github
+//	profile: office
+//	profiles:
+//	  office:
+//	    registry: Gitee
+//	    proxy: http://proxy.company.com:3128
+//	rewrites:
+//	  https://github.com/: https://mirror.company.com/github/
+//	tokens:
+//	  git.company.com: glpat-xxxx
+type File struct {
+	Values   map[string]string
+	Profiles map[string]map[string]string
+	Rewrites map[string]string
+	Tokens   map[string]string
+}
+
+// ReadFile reads the configuration file `path`, a missing file being empty.
+func ReadFile(path string) (*File, error) {
+	f := &File{Values: map[string]string{}, Profiles: map[string]map[string]string{}, Rewrites: map[string]string{}, Tokens: map[string]string{}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	raw := map[string]interface{}{}
+	if err = yaml.Unmarshal(data, &raw); err != nil {
+		return nil, errs.New(errs.Validation, "parse %s failed: %w", path, err)
+	}
+	if f.Values, err = scalars(path, raw); err != nil {
+		return nil, err
+	}
+	if f.Rewrites, err = section(path, raw, "rewrites"); err != nil {
+		return nil, err
+	}
+	if f.Tokens, err = section(path, raw, "tokens"); err != nil {
+		return nil, err
+	}
+	profiles, ok := raw["profiles"]
+	if !ok || profiles == nil {
+		return f, nil
+	}
+	m, ok := profiles.(map[string]interface{})
+	if !ok {
+		return nil, errs.New(errs.Validation, "%s: profiles must be a mapping", path)
+	}
+	for name, p := range m {
+		values, ok := p.(map[string]interface{})
+		if !ok && p != nil {
+			return nil, errs.New(errs.Validation, "%s: profile %s must be a mapping", path, name)
+		}
+		if f.Profiles[name], err = scalars(path, values); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// section returns the scalars of the mapping `name` of `raw`.
+func section(path string, raw map[string]interface{}, name string) (map[string]string, error) {
+	v, ok := raw[name]
+	if !ok || v == nil {
+		return map[string]string{}, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errs.New(errs.Validation, "%s: %s must be a mapping", path, name)
+	}
+	return scalars(path, m)
+}
+
+// scalars returns the scalar values of `raw` as strings, except the sections.
+func scalars(path string, raw map[string]interface{}) (map[string]string, error) {
+	values := make(map[string]string, len(raw))
+	for k, v := range raw {
+		if k == "profiles" || k == "rewrites" || k == "tokens" {
+			continue
+		}
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			return nil, errs.New(errs.Validation, "%s: %s must be a scalar", path, k)
+		case nil:
+			continue
+		}
+		values[k] = fmt.Sprint(v)
+	}
+	return values, nil
+}
+
+// WriteFile writes `f` to the configuration file `path`, sorted by key, or
+// removes the file when empty. Boolean keys are written as booleans.
+func WriteFile(path string, f *File) error {
+	doc := mapping(f.Values)
+	if len(f.Profiles) > 0 {
+		profiles := &yaml.Node{Kind: yaml.MappingNode}
+		for _, name := range sortedKeys(f.Profiles) {
+			profiles.Content = append(profiles.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: name}, mapping(f.Profiles[name]))
+		}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "profiles"}, profiles)
+	}
+	if len(f.Rewrites) > 0 {
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "rewrites"}, mapping(f.Rewrites))
+	}
+	if len(f.Tokens) > 0 {
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "tokens"}, mapping(f.Tokens))
+	}
+	if len(doc.Content) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if len(f.Tokens) == 0 {
+		return os.WriteFile(path, buf.Bytes(), 0644)
+	}
+	// tokens are secrets, also tighten a file that existed before them
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0600)
+}
+
+// mapping returns the yaml mapping of `values`, sorted by key.
+func mapping(values map[string]string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, name := range sortedKeys(values) {
+		value := &yaml.Node{Kind: yaml.ScalarNode, Value: values[name], Tag: "!!str"}
+		if k := LookupKey(name); k != nil && k.Bool {
+			value.Tag = "!!bool"
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, value)
+	}
+	return node
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
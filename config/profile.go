@@ -0,0 +1,61 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package config
+
+// ProfileEnv selects the active profile, like --profile.
+const ProfileEnv = "HB_PROFILE"
+
+// ProfileKeys are the keys a profile is usually made of.
+var ProfileKeys = []string{"registry", "templates", "proxy", "module-prefix", "platform", "ssh-key"}
+
+// Profile is a named set of keys, defined in one or more configuration files.
+type Profile struct {
+	Name string
+	// Values are the merged keys of the profile.
+	Values map[string]string
+	// Files are the files defining the profile, from the lowest precedence.
+	Files []string
+}
+
+// Profiles returns the profiles of every configuration file, sorted by name.
+func Profiles() ([]*Profile, error) {
+	paths, err := Files()
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]*Profile{}
+	for _, path := range paths {
+		f, err := ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		for name, values := range f.Profiles {
+			p := byName[name]
+			if p == nil {
+				p = &Profile{Name: name, Values: map[string]string{}}
+				byName[name] = p
+			}
+			for k, v := range values {
+				p.Values[k] = v
+			}
+			p.Files = append(p.Files, path)
+		}
+	}
+	list := make([]*Profile, 0, len(byName))
+	for _, name := range sortedKeys(byName) {
+		list = append(list, byName[name])
+	}
+	return list, nil
+}
@@ -0,0 +1,141 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+// Package errs classifies the errors of hb commands and maps them to exit codes:
+//
+//	0    success
+//	1    unexpected error
+//	2    invalid usage, flags, answers or template (validation)
+//	3    network failure, e.g. a failed git clone or download
+//	4    missing toolchain, e.g. git or go not installed
+//	5    filesystem permission denied
+//	130  cancelled by the user
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+
+	"github.com/AlecAivazis/survey/v2/terminal"
+)
+
+// Kind classifies an error.
+type Kind int
+
+const (
+	Unknown Kind = iota
+	Validation
+	Network
+	Toolchain
+	Permission
+	Cancelled
+)
+
+var kindNames = map[Kind]string{
+	Unknown:    "unknown",
+	Validation: "validation",
+	Network:    "network",
+	Toolchain:  "toolchain",
+	Permission: "permission",
+	Cancelled:  "cancelled",
+}
+
+var exitCodes = map[Kind]int{
+	Unknown:    1,
+	Validation: 2,
+	Network:    3,
+	Toolchain:  4,
+	Permission: 5,
+	Cancelled:  130,
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// ExitCode returns the process exit code of the errors of kind k.
+func (k Kind) ExitCode() int {
+	return exitCodes[k]
+}
+
+// Error is an error of a known Kind.
+type Error struct {
+	Kind Kind
+	Err  error
+	// reported errors were already shown to the user by the command.
+	reported bool
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns an error of kind `kind` formatted like fmt.Errorf.
+func New(kind Kind, format string, args ...interface{}) error {
+	return &Error{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// Reported returns `err` classified as `kind` and marked as already reported
+// by the command, which only needs hb to exit with the matching code.
+func Reported(kind Kind, err error) error {
+	return &Error{Kind: kind, Err: err, reported: true}
+}
+
+// IsReported reports whether `err` was already shown to the user.
+func IsReported(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.reported
+}
+
+// Wrap classifies `err` as `kind`, unless it is nil or already has a more
+// precise kind, like a missing git binary behind a failed clone.
+func Wrap(kind Kind, err error) error {
+	if err == nil || KindOf(err) != Unknown {
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// KindOf returns the kind of `err`, recognizing besides Error the interrupted
+// prompts, missing executables and permission errors.
+func KindOf(err error) Kind {
+	var e *Error
+	switch {
+	case err == nil:
+		return Unknown
+	case errors.As(err, &e):
+		return e.Kind
+	case errors.Is(err, terminal.InterruptErr):
+		return Cancelled
+	case errors.Is(err, exec.ErrNotFound):
+		return Toolchain
+	case errors.Is(err, fs.ErrPermission):
+		return Permission
+	}
+	return Unknown
+}
+
+// ExitCode returns the process exit code for `err`, 0 when it is nil.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	return KindOf(err).ExitCode()
+}
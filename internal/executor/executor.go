@@ -0,0 +1,134 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+// Package executor runs the external commands of hb, like git and go, with a
+// context, optional timeouts, live or captured output and errors carrying the
+// standard error of the command.
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+	"github.com/winc-link/hummingbird-cli/utility"
+)
+
+// Timeouts of the usual steps.
+const (
+	// NetworkTimeout bounds the commands talking to a remote, like git clone.
+	NetworkTimeout = 10 * time.Minute
+	// LocalTimeout bounds the quick local commands, like git checkout.
+	LocalTimeout = time.Minute
+)
+
+// waitDelay is how long the output of a killed command is still read.
+const waitDelay = 5 * time.Second
+
+// maxStderr is the size of the end of the standard error kept in errors.
+const maxStderr = 4096
+
+// Options controls how a command runs.
+type Options struct {
+	// Dir is the working directory, the current one when empty.
+	Dir string
+	// Env is added to the environment of hb.
+	Env []string
+	// Stdin is the standard input, none when nil.
+	Stdin io.Reader
+	// Timeout kills the command once elapsed, unless zero.
+	Timeout time.Duration
+	// TimeoutKind classifies the error of a timed out command, like Network
+	// for the commands talking to a remote.
+	TimeoutKind errs.Kind
+	// Stream shows the output live, unless --quiet or JSON logs are set.
+	// Otherwise it is captured and written to the trace log.
+	Stream bool
+}
+
+// Run runs `name` with `args` and returns its standard output. The command
+// runs in its own process group, killed as a whole when `ctx` is done or the
+// timeout elapses.
+func Run(ctx context.Context, opts Options, name string, args ...string) ([]byte, error) {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = opts.Dir
+	if len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+	cmd.Stdin = opts.Stdin
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+	cmd.WaitDelay = waitDelay
+
+	var stdout, stderr bytes.Buffer
+	stream := opts.Stream && !utility.Quiet() && utility.Format() == utility.FormatText
+	if stream {
+		cmd.Stdout = io.MultiWriter(os.Stderr, &stdout)
+		cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+	} else {
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+	}
+
+	line := commandLine(name, args)
+	done := utility.Step(line)
+	err := cmd.Run()
+	if !stream {
+		if out := strings.TrimSpace(stdout.String() + stderr.String()); out != "" {
+			utility.Tracef("%s output:\n%s", line, out)
+		}
+	}
+	done()
+
+	if err == nil {
+		return stdout.Bytes(), nil
+	}
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return stdout.Bytes(), errs.New(opts.TimeoutKind, "%s timed out after %s", line, opts.Timeout)
+	case errors.Is(ctx.Err(), context.Canceled):
+		return stdout.Bytes(), errs.New(errs.Cancelled, "%s cancelled", line)
+	}
+	if msg := tail(stderr.String()); msg != "" {
+		return stdout.Bytes(), fmt.Errorf("%s failed: %w: %s", line, err, msg)
+	}
+	return stdout.Bytes(), fmt.Errorf("%s failed: %w", line, err)
+}
+
+func commandLine(name string, args []string) string {
+	return strings.TrimSpace(name + " " + strings.Join(args, " "))
+}
+
+// tail returns the end of the standard error `s`, trimmed.
+func tail(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > maxStderr {
+		s = "..." + s[len(s)-maxStderr:]
+	}
+	return s
+}
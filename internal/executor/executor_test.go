@@ -0,0 +1,43 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package executor
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+)
+
+func TestRunTimeoutKind(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("no sleep command")
+	}
+	for _, kind := range []errs.Kind{errs.Unknown, errs.Network} {
+		opts := Options{Timeout: 100 * time.Millisecond, TimeoutKind: kind}
+		_, err := Run(context.Background(), opts, "sleep", "5")
+		if err == nil || errs.KindOf(err) != kind {
+			t.Errorf("got %v of kind %s, want a %s error", err, errs.KindOf(err), kind)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Run(ctx, Options{}, "sleep", "5"); errs.KindOf(err) != errs.Cancelled {
+		t.Errorf("got %v, want a cancelled error", err)
+	}
+}
@@ -83,7 +83,7 @@ func OpenWithFlagPerm(path string, flag int, perm os.FileMode) (*os.File, error)
 func OpenFile(path string, flag int, perm os.FileMode) (*os.File, error) {
 	file, err := os.OpenFile(path, flag, perm)
 	if err != nil {
-		err = fmt.Errorf("os.OpenFile failed with name %s, flag %d, perm %d ", path, flag, perm)
+		err = fmt.Errorf("os.OpenFile failed with name %s, flag %d, perm %d: %w", path, flag, perm, err)
 	}
 	return file, err
 }
@@ -172,7 +172,7 @@ func Create(path string) (*os.File, error) {
 	}
 	file, err := os.Create(path)
 	if err != nil {
-		err = fmt.Errorf("os.Create failed for name %s", path)
+		err = fmt.Errorf("os.Create failed for name %s: %w", path, err)
 	}
 	return file, err
 }
@@ -181,7 +181,7 @@ func Create(path string) (*os.File, error) {
 func Open(path string) (*os.File, error) {
 	file, err := os.Open(path)
 	if err != nil {
-		err = fmt.Errorf("os.Open failed for name %s", path)
+		err = fmt.Errorf("os.Open failed for name %s: %w", path, err)
 
 	}
 	return file, err
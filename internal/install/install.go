@@ -21,6 +21,7 @@ import (
 	"github.com/gogf/gf/v2/os/gfile"
 	"github.com/gogf/gf/v2/util/gconv"
 	"github.com/spf13/cobra"
+	"github.com/winc-link/hummingbird-cli/internal/errs"
 	"github.com/winc-link/hummingbird-cli/utility"
 	"os"
 	"runtime"
@@ -33,7 +34,14 @@ var CmdInstall = &cobra.Command{
 	Long:    `install hb CLI.`,
 	Short:   `install hb CLI.`,
 
-	Run: run,
+	RunE: run,
+}
+
+// installDir is the folder to install to without asking.
+var installDir string
+
+func init() {
+	CmdInstall.Flags().StringVar(&installDir, "install-dir", installDir, "folder to install hb to, instead of choosing one of $PATH")
 }
 
 type serviceInstallAvailablePath struct {
@@ -59,12 +67,20 @@ func getGoPathBin() string {
 	return ""
 }
 
-func run(cmd *cobra.Command, args []string) {
+func run(cmd *cobra.Command, args []string) error {
+	if installDir != "" {
+		filePath := Join(installDir, "hb"+Ext(SelfPath()))
+		if err := CopyFile(SelfPath(), filePath); err != nil {
+			return fmt.Errorf("install hb binary to '%s' failed: %w", installDir, err)
+		}
+		utility.Resultf("hb binary is successfully installed to: %s", filePath)
+		return nil
+	}
+
 	// Ask where to install.
 	paths := getAvailablePaths()
 	if len(paths) <= 0 {
-		fmt.Printf("no path detected, you can manually install hb by copying the binary to path folder.")
-		return
+		return errs.New(errs.Validation, "no path detected, you can manually install hb by copying the binary to path folder")
 	}
 	utility.Printf("I found some installable paths for you(from $PATH): ")
 	utility.Printf("  %2s | %8s | %9s | %s", "Id", "Writable", "Installed", "Path")
@@ -153,13 +169,11 @@ func run(cmd *cobra.Command, args []string) {
 	utility.Debugf(`copy file from "%s" to "%s"`, gfile.SelfPath(), dstPath.filePath)
 	err := CopyFile(SelfPath(), dstPath.filePath)
 	if err != nil {
-		utility.Printf("install hb binary to '%s' failed: %v", dstPath.dirPath, err)
 		utility.Printf("you can manually install hb by copying the binary to folder: %s", dstPath.dirPath)
-	} else {
-		utility.Printf("hb binary is successfully installed to: %s", dstPath.filePath)
+		return fmt.Errorf("install hb binary to '%s' failed: %w", dstPath.dirPath, err)
 	}
-	return
-
+	utility.Resultf("hb binary is successfully installed to: %s", dstPath.filePath)
+	return nil
 }
 
 // getAvailablePaths returns the installation paths data for the binary.
@@ -0,0 +1,174 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package layout
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// extractArchive extracts the `.tar.gz`, `.tgz` or `.zip` file `path` into `dst`.
+// Archives wrapping everything in a single top-level folder, as produced by
+// GitHub and Gitee downloads, are unwrapped.
+func extractArchive(path, dst string) error {
+	var err error
+	if archiveExt(path) == ".zip" {
+		err = unzip(path, dst)
+	} else {
+		var f *os.File
+		if f, err = os.Open(path); err != nil {
+			return err
+		}
+		err = untarGz(f, dst)
+		f.Close()
+	}
+	if err != nil {
+		return err
+	}
+	return unwrapSingleDir(dst)
+}
+
+// untarGz extracts the gzip compressed tar stream `r` into directory `dst`.
+// Entries escaping `dst` are rejected.
+func untarGz(r io.Reader, dst string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("open gzip stream failed: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read tar entry failed: %w", err)
+		}
+		target, err := safeJoin(dst, hdr.Name)
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(target, tr, os.FileMode(hdr.Mode).Perm()); err != nil {
+				return err
+			}
+		default:
+			// Links and special files are never part of a driver layout.
+		}
+	}
+}
+
+// unzip extracts the zip file `path` into directory `dst`.
+func unzip(path, dst string) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("open zip file failed: %w", err)
+	}
+	defer zr.Close()
+
+	for _, zf := range zr.File {
+		target, err := safeJoin(dst, zf.Name)
+		if err != nil {
+			return err
+		}
+		if zf.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if !zf.Mode().IsRegular() {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return err
+		}
+		err = writeFile(target, rc, zf.Mode().Perm())
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unwrapSingleDir moves the content of `dst/<only-child>` up into `dst`
+// when `dst` contains nothing but one directory.
+func unwrapSingleDir(dst string) error {
+	entries, err := os.ReadDir(dst)
+	if err != nil {
+		return err
+	}
+	if len(entries) != 1 || !entries[0].IsDir() {
+		return nil
+	}
+	// Rename first so a child named like its parent cannot collide.
+	inner := filepath.Join(dst, ".hb-unwrap")
+	if err = os.Rename(filepath.Join(dst, entries[0].Name()), inner); err != nil {
+		return err
+	}
+	children, err := os.ReadDir(inner)
+	if err != nil {
+		return err
+	}
+	for _, c := range children {
+		if err := os.Rename(filepath.Join(inner, c.Name()), filepath.Join(dst, c.Name())); err != nil {
+			return err
+		}
+	}
+	return os.Remove(inner)
+}
+
+// safeJoin joins `name` to `dst` and makes sure the result stays inside `dst`.
+func safeJoin(dst, name string) (string, error) {
+	target := filepath.Join(dst, filepath.FromSlash(name))
+	rel, err := filepath.Rel(dst, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("illegal path %s in archive", name)
+	}
+	return target, nil
+}
+
+func writeFile(path string, r io.Reader, perm os.FileMode) error {
+	if perm == 0 {
+		perm = 0644
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,107 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package layout
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSafeJoin(t *testing.T) {
+	dst := filepath.Join(t.TempDir(), "demo")
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"main.go", filepath.Join(dst, "main.go"), true},
+		{"internal/server/server.go", filepath.Join(dst, "internal", "server", "server.go"), true},
+		{"./go.mod", filepath.Join(dst, "go.mod"), true},
+		{"a/../b.go", filepath.Join(dst, "b.go"), true},
+		{"..foo/x.go", filepath.Join(dst, "..foo", "x.go"), true},
+		// Absolute names are joined, so they stay inside dst.
+		{"/etc/passwd", filepath.Join(dst, "etc", "passwd"), true},
+		{"../evil.go", "", false},
+		{"a/../../evil.go", "", false},
+		{"..", "", false},
+		{"../demo2/x.go", "", false},
+	}
+	for _, tt := range tests {
+		got, err := safeJoin(dst, tt.name)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("safeJoin(%q) = %q, %v, want %q, ok %v", tt.name, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestUntarGzRejectsEscapes(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, hdr := range []*tar.Header{
+		{Name: "link", Linkname: "/etc", Typeflag: tar.TypeSymlink},
+		{Name: "../evil.go", Mode: 0644, Size: 1, Typeflag: tar.TypeReg},
+	} {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Size > 0 {
+			tw.Write([]byte("x"))
+		}
+	}
+	tw.Close()
+	gz.Close()
+
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "demo")
+	if err := untarGz(&buf, dst); err == nil {
+		t.Fatal("archive escaping its folder extracted")
+	}
+	if _, err := os.Lstat(filepath.Join(dst, "link")); err == nil {
+		t.Error("symbolic link extracted")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil.go")); err == nil {
+		t.Error("file written outside the folder")
+	}
+}
+
+func TestUnzipRejectsEscapes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "t.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, err := zw.Create("../evil.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("x"))
+	zw.Close()
+	f.Close()
+
+	if err = unzip(path, filepath.Join(dir, "demo")); err == nil {
+		t.Fatal("archive escaping its folder extracted")
+	}
+	if _, err = os.Stat(filepath.Join(dir, "evil.go")); err == nil {
+		t.Error("file written outside the folder")
+	}
+}
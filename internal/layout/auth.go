@@ -0,0 +1,276 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package layout
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/winc-link/hummingbird-cli/internal/executor"
+	"github.com/winc-link/hummingbird-cli/utility"
+)
+
+// TokenEnvPrefix prefixes the environment variables holding the token of a
+// host: HB_TOKEN_GIT_COMPANY_COM for git.company.com.
+const TokenEnvPrefix = "HB_TOKEN_"
+
+// defaultTokenUser is the user sent with a token, accepted by GitHub, GitLab
+// and Gitee. A token written "user:token" sets its own user.
+const defaultTokenUser = "oauth2"
+
+var (
+	// tokens are the tokens of the configuration, by host.
+	tokens map[string]string
+	// sshKey is the private key used for the SSH remotes, "" for the ssh default.
+	sshKey string
+)
+
+// SetTokens sets the tokens of the configuration, by host.
+func SetTokens(byHost map[string]string) {
+	tokens = byHost
+}
+
+// SetSSHKey sets the private key used for the SSH remotes.
+func SetSSHKey(path string) {
+	sshKey = path
+}
+
+// TokenEnv returns the environment variable holding the token of `host`.
+func TokenEnv(host string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, host)
+	return TokenEnvPrefix + strings.ToUpper(name)
+}
+
+// credential returns the user and password or token of `host`, from, in
+// order, its environment variable, the configuration and the netrc file.
+func credential(host string) (user, password string, ok bool) {
+	token := os.Getenv(TokenEnv(host))
+	if token == "" {
+		token = tokens[host]
+	}
+	if token != "" {
+		if u, t, found := strings.Cut(token, ":"); found {
+			return u, t, true
+		}
+		return defaultTokenUser, token, true
+	}
+	return netrc(host)
+}
+
+// splitCredentials removes the user info of an HTTP(S) URL and returns it apart.
+// Other URLs, e.g. ssh://git@host/org/repo, are returned as is.
+func splitCredentials(raw string) (string, *url.Userinfo) {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil || u.Scheme != "http" && u.Scheme != "https" {
+		return raw, nil
+	}
+	user := u.User
+	u.User = nil
+	return u.String(), user
+}
+
+// StripCredentials returns `raw` without the user info of an HTTP(S) URL,
+// so that tokens are not written to the answers or lock files.
+func StripCredentials(raw string) string {
+	for _, prefix := range []string{"git+", ""} {
+		if strings.HasPrefix(raw, prefix+"http://") || strings.HasPrefix(raw, prefix+"https://") {
+			clean, _ := splitCredentials(strings.TrimPrefix(raw, prefix))
+			return prefix + clean
+		}
+	}
+	return raw
+}
+
+// basicAuth returns the credentials to send to HTTP(S) `remote`: the ones of
+// the URL, if any, or the ones of its host.
+func basicAuth(remote string, embedded *url.Userinfo) (user, password string, ok bool) {
+	if embedded != nil {
+		password, hasPassword := embedded.Password()
+		if !hasPassword {
+			// https://<token>@host/org/repo
+			return defaultTokenUser, embedded.Username(), true
+		}
+		return embedded.Username(), password, true
+	}
+	u, err := url.Parse(remote)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" {
+		return "", "", false
+	}
+	return credential(u.Hostname())
+}
+
+// gitEnv returns the environment of a git command talking to `remote`. Git
+// never prompts, since it runs in its own process group, but its credential
+// helpers still answer. A known token is sent as an HTTP header set through
+// the environment, so that it neither shows in the command line nor is
+// stored in the repository.
+func gitEnv(remote string, embedded *url.Userinfo) []string {
+	env := []string{"GIT_TERMINAL_PROMPT=0"}
+	if isSSH(remote) {
+		if sshKey != "" {
+			env = append(env, "GIT_SSH_COMMAND=ssh -i "+strconv.Quote(expandHome(sshKey))+" -o IdentitiesOnly=yes -o BatchMode=yes")
+		} else if os.Getenv("GIT_SSH_COMMAND") == "" && os.Getenv("GIT_SSH") == "" {
+			env = append(env, "GIT_SSH_COMMAND=ssh -o BatchMode=yes")
+		}
+		return env
+	}
+	user, password, ok := basicAuth(remote, embedded)
+	if !ok {
+		return env
+	}
+	u, _ := url.Parse(remote)
+	n, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	header := "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+	utility.Debugf("send the credentials of %s to %s", user, u.Host)
+	return append(env,
+		"GIT_CONFIG_COUNT="+strconv.Itoa(n+1),
+		"GIT_CONFIG_KEY_"+strconv.Itoa(n)+"=http."+u.Scheme+"://"+u.Host+"/.extraHeader",
+		"GIT_CONFIG_VALUE_"+strconv.Itoa(n)+"="+header,
+	)
+}
+
+// isSSH reports whether `remote` is reached over SSH.
+func isSSH(remote string) bool {
+	if strings.HasPrefix(remote, "ssh://") || strings.HasPrefix(remote, "git+ssh://") {
+		return true
+	}
+	// scp-like git@host:org/repo
+	at, colon := strings.Index(remote, "@"), strings.Index(remote, ":")
+	return !strings.Contains(remote, "://") && at != -1 && colon > at
+}
+
+// gitCredential asks the credential helpers of git for the credentials of
+// HTTP(S) `u`, with `action` fill, approve or reject.
+func gitCredential(ctx context.Context, action string, u *url.URL, user, password string) (string, string, bool) {
+	var in strings.Builder
+	in.WriteString("protocol=" + u.Scheme + "\nhost=" + u.Host + "\npath=" + strings.TrimPrefix(u.Path, "/") + "\n")
+	if user != "" {
+		in.WriteString("username=" + user + "\npassword=" + password + "\n")
+	}
+	in.WriteString("\n")
+	opts := executor.Options{
+		Env:     []string{"GIT_TERMINAL_PROMPT=0"},
+		Stdin:   strings.NewReader(in.String()),
+		Timeout: executor.LocalTimeout,
+	}
+	out, err := executor.Run(ctx, opts, "git", "credential", action)
+	if err != nil || action != "fill" {
+		return "", "", false
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		k, v, _ := strings.Cut(line, "=")
+		switch k {
+		case "username":
+			user = v
+		case "password":
+			password = v
+		}
+	}
+	return user, password, password != ""
+}
+
+// netrc returns the login and password of `host` in $NETRC or ~/.netrc.
+func netrc(host string) (login, password string, ok bool) {
+	path := os.Getenv("NETRC")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", false
+		}
+		path = filepath.Join(home, ".netrc")
+		if runtime.GOOS == "windows" {
+			path = filepath.Join(home, "_netrc")
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", "", false
+	}
+	defer f.Close()
+
+	var fields []string
+	macro := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if macro {
+			// A macro runs until an empty line.
+			macro = line != ""
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		words := strings.Fields(line)
+		for i, w := range words {
+			if w == "macdef" {
+				words, macro = words[:i], true
+				break
+			}
+		}
+		fields = append(fields, words...)
+	}
+	// Entries are "machine <host> login <user> password <secret>" or
+	// "default login <user> password <secret>", on one or more lines.
+	matched, found := false, false
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "machine":
+			if found {
+				return login, password, password != ""
+			}
+			i++
+			matched = i < len(fields) && fields[i] == host
+			found = matched
+		case "default":
+			if found {
+				return login, password, password != ""
+			}
+			matched, found = true, true
+		case "login", "password", "account":
+			i++
+			if !matched || i >= len(fields) {
+				continue
+			}
+			if fields[i-1] == "login" {
+				login = fields[i]
+			} else if fields[i-1] == "password" {
+				password = fields[i]
+			}
+		}
+	}
+	return login, password, found && password != ""
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
@@ -0,0 +1,437 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package layout
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+	"github.com/winc-link/hummingbird-cli/internal/executor"
+	"github.com/winc-link/hummingbird-cli/utility"
+)
+
+// commitHash matches a full SHA-1 or SHA-256 commit hash.
+var commitHash = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+
+// mirrorDir is the bare git mirror of a git source inside its cache entry.
+const mirrorDir = "mirror.git"
+
+// Cache keeps fetched templates under the user cache folder, so that `hb new`
+// only downloads the changes of a git template, and needs no network at all
+// for tags, commits and archives it has seen before:
+//
+//	<dir>/<key>/source        spec of the source, key is derived from it
+//	<dir>/<key>/mirror.git    bare mirror of a git source
+//	<dir>/<key>/archive.*     downloaded archive of an HTTP source
+//	<dir>/<key>/<revision>/   template tree at a commit or archive digest
+//	<dir>/.locks/<key>.lock   file lock of the entry
+//
+// An entry is only changed under its exclusive file lock, so parallel hb
+// processes can share the cache and only wait for each other on the same
+// source.
+type Cache struct {
+	Dir string
+}
+
+// CacheEntry is a cached template revision, or the mirror of a git source.
+type CacheEntry struct {
+	Source   string
+	Revision string
+	Path     string
+	Size     int64
+	LastUsed time.Time
+}
+
+// OpenCache returns the cache in `<user cache dir>/hb/templates`.
+func OpenCache() (*Cache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	dir = filepath.Join(dir, "hb", "templates")
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Cache{Dir: dir}, nil
+}
+
+// Fetch writes the template of `src` into `dst` through the cache. Git and
+// HTTP sources are cached, other sources are local and fetched directly.
+func (c *Cache) Fetch(ctx context.Context, src TemplateSource, dst string) error {
+	snapshot, err := c.prepare(ctx, src, false)
+	if err != nil {
+		return err
+	}
+	if snapshot == "" {
+		return src.Fetch(ctx, dst)
+	}
+	return CopyDir(snapshot, dst, nil)
+}
+
+// Update refreshes the cached copy of `src` from the network: the git mirror
+// is fetched or the archive downloaded again.
+func (c *Cache) Update(ctx context.Context, src TemplateSource) error {
+	_, err := c.prepare(ctx, src, true)
+	return err
+}
+
+// prepare makes sure the revision of `src` is cached and returns its folder,
+// or "" if the source is not cached.
+func (c *Cache) prepare(ctx context.Context, src TemplateSource, update bool) (string, error) {
+	switch src.(type) {
+	case *GitSource, *HTTPSource:
+	default:
+		return "", nil
+	}
+	unlock, err := c.lock(cacheKey(src.Spec()))
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	if s, ok := src.(*GitSource); ok {
+		return c.prepareGit(ctx, s, update)
+	}
+	return c.prepareHTTP(ctx, src.(*HTTPSource), update)
+}
+
+func (c *Cache) prepareGit(ctx context.Context, s *GitSource, update bool) (string, error) {
+	entry, err := c.entry(s.Spec())
+	if err != nil {
+		return "", err
+	}
+	mirror := filepath.Join(entry, mirrorDir)
+	fetched := false
+	remote, user := remoteURL(s.URL)
+	if _, err = os.Stat(mirror); os.IsNotExist(err) {
+		tmp := mirror + ".tmp"
+		os.RemoveAll(tmp)
+		if err = probe(ctx, remote); err != nil {
+			return "", err
+		}
+		if _, err = remoteGit(ctx, "", remote, user, "clone", "--mirror", "--progress", remote, tmp); err != nil {
+			return "", errs.Wrap(errs.Network, err)
+		}
+		if err = os.Rename(tmp, mirror); err != nil {
+			return "", err
+		}
+		fetched = true
+	} else if update || !pinned(ctx, mirror, s.Ref) {
+		// Branches and HEAD move, fetch them; offline the mirror is used as is.
+		if err = updateMirror(ctx, mirror, remote, user); err == nil {
+			fetched = true
+		} else if update || errs.KindOf(err) != errs.Network {
+			return "", err
+		} else {
+			utility.Warnf("update %s failed: %s, use the cached template", remote, err)
+		}
+	}
+
+	commit, err := resolveCommit(ctx, mirror, s.Ref)
+	if err != nil && !fetched {
+		// The ref may be newer than the mirror.
+		if err = updateMirror(ctx, mirror, remote, user); err != nil {
+			return "", err
+		}
+		commit, err = resolveCommit(ctx, mirror, s.Ref)
+	}
+	if err != nil {
+		return "", err
+	}
+	touch(mirror)
+
+	snapshot := filepath.Join(entry, commit)
+	if _, err = os.Stat(snapshot); os.IsNotExist(err) {
+		tmp := snapshot + ".tmp"
+		os.RemoveAll(tmp)
+		if _, err = git(ctx, "", "clone", "-q", "--no-checkout", mirror, tmp); err != nil {
+			return "", err
+		}
+		if _, err = git(ctx, tmp, "checkout", "-q", commit); err != nil {
+			return "", err
+		}
+		if err = os.RemoveAll(filepath.Join(tmp, ".git")); err != nil {
+			return "", err
+		}
+		if err = os.Rename(tmp, snapshot); err != nil {
+			return "", err
+		}
+	}
+	touch(snapshot)
+	s.commit = commit
+	return snapshot, nil
+}
+
+func (c *Cache) prepareHTTP(ctx context.Context, s *HTTPSource, update bool) (string, error) {
+	entry, err := c.entry(s.Spec())
+	if err != nil {
+		return "", err
+	}
+	archive := filepath.Join(entry, "archive"+archiveExt(s.URL))
+	if _, err = os.Stat(archive); os.IsNotExist(err) || update {
+		remote, user := remoteURL(s.URL)
+		if err = download(ctx, remote, user, archive); err != nil {
+			return "", err
+		}
+	}
+	digest, err := fileDigest(archive)
+	if err != nil {
+		return "", err
+	}
+	snapshot := filepath.Join(entry, strings.TrimPrefix(digest, "sha256:"))
+	if _, err = os.Stat(snapshot); os.IsNotExist(err) {
+		tmp := snapshot + ".tmp"
+		os.RemoveAll(tmp)
+		if err = extractArchive(archive, tmp); err != nil {
+			return "", err
+		}
+		if err = os.Rename(tmp, snapshot); err != nil {
+			return "", err
+		}
+	}
+	touch(snapshot)
+	s.digest = digest
+	return snapshot, nil
+}
+
+// cacheKey returns the name of the cache entry of source `spec`.
+func cacheKey(spec string) string {
+	sum := sha256.Sum256([]byte(spec))
+	return hex.EncodeToString(sum[:8])
+}
+
+// entry returns the cache folder of source `spec`, creating it if needed.
+// The caller holds the lock of the entry.
+func (c *Cache) entry(spec string) (string, error) {
+	dir := filepath.Join(c.Dir, cacheKey(spec))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, os.WriteFile(filepath.Join(dir, "source"), []byte(spec+"\n"), 0644)
+}
+
+// Entries lists the cached revisions and mirrors, most recently used first.
+// It takes no lock, revisions being written are left out.
+func (c *Cache) Entries() ([]CacheEntry, error) {
+	keys, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var list []CacheEntry
+	for _, key := range keys {
+		if !key.IsDir() || strings.HasPrefix(key.Name(), ".") {
+			continue
+		}
+		dir := filepath.Join(c.Dir, key.Name())
+		spec, _ := os.ReadFile(filepath.Join(dir, "source"))
+		children, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			if !child.IsDir() || strings.HasSuffix(child.Name(), ".tmp") {
+				continue
+			}
+			info, err := child.Info()
+			if err != nil {
+				return nil, err
+			}
+			revision := child.Name()
+			if revision == mirrorDir {
+				revision = "(mirror)"
+			}
+			path := filepath.Join(dir, child.Name())
+			list = append(list, CacheEntry{
+				Source:   strings.TrimSpace(string(spec)),
+				Revision: revision,
+				Path:     path,
+				Size:     dirSize(path),
+				LastUsed: info.ModTime(),
+			})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].LastUsed.After(list[j].LastUsed)
+	})
+	return list, nil
+}
+
+// Prune removes the entries not used for `olderThan` and returns them.
+func (c *Cache) Prune(olderThan time.Duration) ([]CacheEntry, error) {
+	list, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var pruned []CacheEntry
+	deadline := time.Now().Add(-olderThan)
+	for _, e := range list {
+		if e.LastUsed.After(deadline) {
+			continue
+		}
+		removed, err := c.prune(e, deadline)
+		if err != nil {
+			return pruned, err
+		}
+		if removed {
+			pruned = append(pruned, e)
+		}
+	}
+	return pruned, nil
+}
+
+// prune removes `e` under the lock of its entry, unless it was used after
+// `deadline` in the meantime.
+func (c *Cache) prune(e CacheEntry, deadline time.Time) (bool, error) {
+	dir := filepath.Dir(e.Path)
+	unlock, err := c.lock(filepath.Base(dir))
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	if info, err := os.Stat(e.Path); err != nil || info.ModTime().After(deadline) {
+		return false, nil
+	}
+	if err = os.RemoveAll(e.Path); err != nil {
+		return false, err
+	}
+	// Drop the source entry once nothing is left in it.
+	if children, _ := os.ReadDir(dir); len(children) == 1 && children[0].Name() == "source" {
+		os.RemoveAll(dir)
+	}
+	return true, nil
+}
+
+// lock takes the exclusive lock of the cache entry `key` and returns its
+// release function. The lock files live outside the entries, so removing an
+// entry never drops a lock another process waits on.
+func (c *Cache) lock(key string) (func(), error) {
+	dir := filepath.Join(c.Dir, ".locks")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, key+".lock"), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err = lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("lock template cache failed: %w", err)
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// updateMirror fetches the git mirror `mirror` from `remote`, which may have
+// changed since the clone because of the rewrite rules.
+func updateMirror(ctx context.Context, mirror, remote string, user *url.Userinfo) error {
+	if _, err := git(ctx, mirror, "remote", "set-url", "origin", remote); err != nil {
+		return err
+	}
+	if err := probe(ctx, remote); err != nil {
+		return err
+	}
+	if _, err := remoteGit(ctx, mirror, remote, user, "remote", "update", "--prune"); err != nil {
+		return errs.Wrap(errs.Network, err)
+	}
+	return nil
+}
+
+// pinned reports whether `ref` is a full commit hash or a tag of `repo`, whose
+// cached snapshot can be used without fetching the mirror.
+func pinned(ctx context.Context, repo, ref string) bool {
+	if commitHash.MatchString(ref) {
+		return true
+	}
+	if ref == "" || ref == "HEAD" {
+		return false
+	}
+	_, err := git(ctx, repo, "rev-parse", "--verify", "-q", "refs/tags/"+ref)
+	return err == nil
+}
+
+// resolveCommit returns the commit `ref`, or HEAD when empty, points to in `repo`.
+func resolveCommit(ctx context.Context, repo, ref string) (string, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	out, err := git(ctx, repo, "rev-parse", "--verify", "-q", ref+"^{commit}")
+	if err != nil {
+		if errs.KindOf(err) == errs.Cancelled {
+			return "", err
+		}
+		return "", errs.New(errs.Validation, "unknown revision %s", ref)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// download saves `remote` to `path`.
+func download(ctx context.Context, remote string, user *url.Userinfo, path string) error {
+	if err := probe(ctx, remote); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, executor.NetworkTimeout)
+	defer cancel()
+	resp, err := get(ctx, remote, user)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errs.New(errs.Network, "download %s failed: %s", remote, resp.Status)
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return errs.Wrap(errs.Network, err)
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func touch(path string) {
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.Walk(dir, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
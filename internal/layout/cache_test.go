@@ -0,0 +1,64 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package layout
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCacheLockPerEntry(t *testing.T) {
+	c := &Cache{Dir: t.TempDir()}
+	unlock, err := c.lock("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	locked := func(key string) chan struct{} {
+		done := make(chan struct{})
+		go func() {
+			if release, err := c.lock(key); err == nil {
+				release()
+			}
+			close(done)
+		}()
+		return done
+	}
+
+	select {
+	case <-locked("b"):
+	case <-time.After(5 * time.Second):
+		t.Fatal("the lock of another entry waits")
+	}
+	same := locked("a")
+	select {
+	case <-same:
+		t.Fatal("the lock of the same entry does not wait")
+	case <-time.After(100 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case <-same:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the lock is not released")
+	}
+
+	entries, err := c.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("got %v, want no entry for the lock files", entries)
+	}
+}
@@ -0,0 +1,242 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package layout
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around a change.
+const diffContext = 3
+
+// UnifiedDiff returns the unified diff turning `a` into `b`, with both sides
+// labelled `name`, or "" when they are equal.
+func UnifiedDiff(name string, a, b []byte) string {
+	x, y := splitLines(string(a)), splitLines(string(b))
+	ops := diffLines(x, y)
+
+	var sb strings.Builder
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// A hunk spans the changes separated by at most 2*diffContext equal lines.
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(ops); j++ {
+			if ops[j].kind != ' ' {
+				end = j + 1
+			} else if j-end >= 2*diffContext {
+				break
+			}
+		}
+		end += diffContext
+		if end > len(ops) {
+			end = len(ops)
+		}
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- a/%s\n+++ b/%s\n", name, name)
+		}
+		oldStart, newStart, oldLen, newLen := ops[start].x+1, ops[start].y+1, 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				oldLen++
+			}
+			if op.kind != '-' {
+				newLen++
+			}
+		}
+		if oldLen == 0 {
+			oldStart--
+		}
+		if newLen == 0 {
+			newStart--
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", oldStart, oldLen, newStart, newLen)
+		for _, op := range ops[start:end] {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+	return sb.String()
+}
+
+// diffOp is one line of a diff: kept (' '), removed ('-') or added ('+'),
+// at index x of the old lines and y of the new lines.
+type diffOp struct {
+	kind byte
+	line string
+	x, y int
+}
+
+// diffLines computes a shortest edit script with the linear space variant
+// of the Myers algorithm, so that large generated files stay cheap to diff.
+func diffLines(x, y []string) []diffOp {
+	d := &differ{x: x, y: y}
+	d.diff(0, len(x), 0, len(y))
+	return d.ops
+}
+
+// differ accumulates the edit script of x into y.
+type differ struct {
+	x, y []string
+	ops  []diffOp
+}
+
+// diff appends the edit script turning x[i0:i1] into y[j0:j1].
+func (d *differ) diff(i0, i1, j0, j1 int) {
+	for i0 < i1 && j0 < j1 && d.x[i0] == d.y[j0] {
+		d.ops = append(d.ops, diffOp{' ', d.x[i0], i0, j0})
+		i0++
+		j0++
+	}
+	suffix := 0
+	for i1 > i0 && j1 > j0 && d.x[i1-1] == d.y[j1-1] {
+		i1--
+		j1--
+		suffix++
+	}
+	if i0 == i1 || j0 == j1 {
+		d.replace(i0, i1, j0, j1)
+	} else if i, j, ok := d.bisect(i0, i1, j0, j1); ok {
+		d.diff(i0, i, j0, j)
+		d.diff(i, i1, j, j1)
+	} else {
+		d.replace(i0, i1, j0, j1)
+	}
+	for k := 0; k < suffix; k++ {
+		d.ops = append(d.ops, diffOp{' ', d.x[i1+k], i1 + k, j1 + k})
+	}
+}
+
+// replace appends the removal of x[i0:i1] and the addition of y[j0:j1].
+func (d *differ) replace(i0, i1, j0, j1 int) {
+	for i := i0; i < i1; i++ {
+		d.ops = append(d.ops, diffOp{'-', d.x[i], i, j0})
+	}
+	for j := j0; j < j1; j++ {
+		d.ops = append(d.ops, diffOp{'+', d.y[j], i1, j})
+	}
+}
+
+// bisect finds the middle of a shortest edit path of x[i0:i1] into y[j0:j1]
+// by searching from both ends at once, see "An O(ND) Difference Algorithm
+// and Its Variations", Myers 1986. ok is false when the ranges share nothing.
+func (d *differ) bisect(i0, i1, j0, j1 int) (i, j int, ok bool) {
+	n, m := i1-i0, j1-j0
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	// forward[k] and backward[k] are the furthest x reached on diagonal k
+	// from the start and from the end, -1 when not reached yet.
+	forward := make([]int, 2*maxD+2)
+	backward := make([]int, 2*maxD+2)
+	for k := range forward {
+		forward[k], backward[k] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+	delta := n - m
+	odd := delta%2 != 0
+	// The diagonals leaving the grid are skipped in the next rounds.
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+	for D := 0; D < maxD; D++ {
+		for k := -D + fStart; k <= D-fEnd; k += 2 {
+			var x int
+			if k == -D || k != D && forward[offset+k-1] < forward[offset+k+1] {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.x[i0+x] == d.y[j0+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case odd:
+				if b := offset + delta - k; b >= 0 && b < len(backward) && backward[b] != -1 && x >= n-backward[b] {
+					return d.split(i0, i1, j0, j1, x, y)
+				}
+			}
+		}
+		for k := -D + bStart; k <= D-bEnd; k += 2 {
+			var x int
+			if k == -D || k != D && backward[offset+k-1] < backward[offset+k+1] {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.x[i1-x-1] == d.y[j1-y-1] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !odd:
+				if f := offset + delta - k; f >= 0 && f < len(forward) && forward[f] != -1 {
+					fx := forward[f]
+					if fx >= n-x {
+						return d.split(i0, i1, j0, j1, fx, fx-(f-offset))
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// split returns the point x, y of the ranges as absolute indexes, unless it
+// is one of their ends, which would not make the problem any smaller.
+func (d *differ) split(i0, i1, j0, j1, x, y int) (int, int, bool) {
+	i, j := i0+x, j0+y
+	if i == i0 && j == j0 || i == i1 && j == j1 {
+		return 0, 0, false
+	}
+	return i, j, true
+}
+
+// splitLines splits `s` after every newline.
+func splitLines(s string) []string {
+	var lines []string
+	for s != "" {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			lines = append(lines, s)
+			break
+		}
+		lines = append(lines, s[:i+1])
+		s = s[i+1:]
+	}
+	return lines
+}
@@ -0,0 +1,125 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package layout
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "package main\n\nimport (\n\t\"fmt\"\n\n\t\"driver/internal/server\"\n)\n\nfunc main() {\n\tfmt.Println(server.Name)\n}\n"
+	b := strings.Replace(a, "driver/", "example.com/demo/", 1)
+	want := `--- a/main.go
++++ b/main.go
+@@ -3,7 +3,7 @@
+ import (
+ 	"fmt"
+ 
+-	"driver/internal/server"
++	"example.com/demo/internal/server"
+ )
+ 
+ func main() {
+`
+	if got := UnifiedDiff("main.go", []byte(a), []byte(b)); got != want {
+		t.Errorf("UnifiedDiff =\n%s\nwant\n%s", got, want)
+	}
+	if got := UnifiedDiff("main.go", []byte(a), []byte(a)); got != "" {
+		t.Errorf("UnifiedDiff of equal files =\n%s", got)
+	}
+}
+
+func TestDiffLinesShortest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 500; n++ {
+		x, y := randomLines(r, r.Intn(30)), randomLines(r, r.Intn(30))
+		ops := diffLines(x, y)
+		checkScript(t, x, y, ops)
+		edits := 0
+		for _, op := range ops {
+			if op.kind != ' ' {
+				edits++
+			}
+		}
+		if want := len(x) + len(y) - 2*lcsLength(x, y); edits != want {
+			t.Fatalf("diff of %q and %q has %d edits, want %d", x, y, edits, want)
+		}
+	}
+}
+
+func TestDiffLinesLarge(t *testing.T) {
+	x := make([]string, 50000)
+	for i := range x {
+		x[i] = fmt.Sprintf("line %d\n", i)
+	}
+	y := append([]string(nil), x...)
+	y[10] = "changed\n"
+	y = append(y[:25000], y[25100:]...)
+	checkScript(t, x, y, diffLines(x, y))
+}
+
+// checkScript checks `ops` turns x into y.
+func checkScript(t *testing.T, x, y []string, ops []diffOp) {
+	t.Helper()
+	var gotX, gotY []string
+	for _, op := range ops {
+		if op.kind != '+' {
+			if op.x != len(gotX) {
+				t.Fatalf("op %q at x %d, want %d", op.line, op.x, len(gotX))
+			}
+			gotX = append(gotX, op.line)
+		}
+		if op.kind != '-' {
+			if op.y != len(gotY) {
+				t.Fatalf("op %q at y %d, want %d", op.line, op.y, len(gotY))
+			}
+			gotY = append(gotY, op.line)
+		}
+	}
+	if strings.Join(gotX, "") != strings.Join(x, "") || strings.Join(gotY, "") != strings.Join(y, "") {
+		t.Fatalf("script does not turn %q into %q", x, y)
+	}
+}
+
+func randomLines(r *rand.Rand, n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = string(rune('a'+r.Intn(4))) + "\n"
+	}
+	return lines
+}
+
+// lcsLength is the quadratic reference for the length of the longest common
+// subsequence.
+func lcsLength(x, y []string) int {
+	prev, cur := make([]int, len(y)+1), make([]int, len(y)+1)
+	for i := range x {
+		for j := range y {
+			switch {
+			case x[i] == y[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] >= cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(y)]
+}
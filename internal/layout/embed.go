@@ -0,0 +1,90 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package layout
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+)
+
+//go:generate go run gen_templates.go
+
+// The snapshot is refreshed with `go generate` or `make templates`, which
+// packs every protocol template as `templates/<repo>.tar.gz`. Release builds
+// are built with -tags release and fail unless every snapshot is present,
+// see embed_release.go.
+//
+//go:embed templates
+var embedded embed.FS
+
+// EmbeddedRegistry is the registry name of the embedded sources in the template registry.
+const EmbeddedRegistry = "Embedded"
+
+// ErrNotEmbedded is returned when the requested template is not part of this build.
+var ErrNotEmbedded error = &errs.Error{Kind: errs.Validation, Err: errors.New("template is not embedded in this build, run `make templates` and rebuild hb with -tags release")}
+
+// Embedded returns the names of all templates bundled into the binary.
+func Embedded() []string {
+	entries, err := fs.ReadDir(embedded, "templates")
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		if name, ok := strings.CutSuffix(e.Name(), ".tar.gz"); ok && !e.IsDir() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isEmbedded reports whether the snapshot of template `name` is part of this build.
+func isEmbedded(name string) bool {
+	_, err := fs.Stat(embedded, "templates/"+name+".tar.gz")
+	return err == nil
+}
+
+// embeddedDigest returns the sha256 digest of the snapshot of template `name`.
+func embeddedDigest(name string) (string, error) {
+	f, err := embedded.Open("templates/" + name + ".tar.gz")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("%s: %w", name, ErrNotEmbedded)
+		}
+		return "", err
+	}
+	defer f.Close()
+	return digest(f)
+}
+
+// ExtractEmbedded writes the embedded snapshot of template `name` into `dst`.
+func ExtractEmbedded(name, dst string) error {
+	f, err := embedded.Open("templates/" + name + ".tar.gz")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%s: %w", name, ErrNotEmbedded)
+		}
+		return err
+	}
+	defer f.Close()
+	return untarGz(f, dst)
+}
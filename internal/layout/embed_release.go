@@ -0,0 +1,20 @@
+// Code generated by gen_templates.go; DO NOT EDIT.
+
+//go:build release
+
+package layout
+
+import "embed"
+
+// releaseSnapshots fails the release builds, built with -tags release,
+// unless every template of the built-in registry is embedded.
+//
+//go:embed templates/hummingbird-mqtt-driver.tar.gz
+//go:embed templates/hummingbird-tcp-driver.tar.gz
+//go:embed templates/hummingbird-udp-driver.tar.gz
+//go:embed templates/hummingbird-coap-driver.tar.gz
+//go:embed templates/hummingbird-http-driver.tar.gz
+//go:embed templates/hummingbird-websocket-driver.tar.gz
+//go:embed templates/hummingbird-modbus-driver.tar.gz
+//go:embed templates/hummingbird-opcua-driver.tar.gz
+var releaseSnapshots embed.FS
@@ -0,0 +1,51 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package layout
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestReleaseSnapshots checks embed_release.go requires a snapshot of every
+// embedded template of the built-in registry; run `go generate` if it fails.
+func TestReleaseSnapshots(t *testing.T) {
+	data, err := os.ReadFile("embed_release.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if name, ok := strings.CutPrefix(line, "//go:embed templates/"); ok {
+			got = append(got, strings.TrimSuffix(name, ".tar.gz"))
+		}
+	}
+	var want []string
+	for _, tpl := range DefaultRegistry().Templates {
+		for _, s := range tpl.Sources {
+			if s.Registry == EmbeddedRegistry {
+				want = append(want, strings.TrimPrefix(s.URL, "embedded:"))
+			}
+		}
+	}
+	sort.Strings(got)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("embed_release.go embeds %v, the registry %v", got, want)
+	}
+}
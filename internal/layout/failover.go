@@ -0,0 +1,208 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package layout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+	"github.com/winc-link/hummingbird-cli/utility"
+)
+
+// ProbeTimeout bounds the connectivity probe of a remote.
+const ProbeTimeout = 5 * time.Second
+
+// Candidate is one place a template can be fetched from.
+type Candidate struct {
+	Registry string
+	Source   TemplateSource
+}
+
+// Candidates returns the sources of the template in the order they are
+// tried: the URL and mirrors of `registry` first, then, unless `only`, the
+// ones of the other registries in the order of the template.
+func (t *Template) Candidates(registry string, only bool) ([]Candidate, error) {
+	var first, rest []Candidate
+	for _, loc := range t.Sources {
+		chosen := strings.EqualFold(loc.Registry, registry)
+		if !chosen && only {
+			continue
+		}
+		for _, u := range append([]string{loc.URL}, loc.Mirrors...) {
+			src, err := ParseSource(u)
+			if err != nil {
+				return nil, err
+			}
+			if g, ok := src.(*GitSource); ok && g.Ref == "" {
+				g.Ref = t.Ref
+			}
+			if e, ok := src.(*EmbeddedSource); ok && !chosen && !isEmbedded(e.Name) {
+				// Not part of this build, it cannot stand in for the network.
+				continue
+			}
+			c := Candidate{Registry: loc.Registry, Source: src}
+			if chosen {
+				first = append(first, c)
+			} else {
+				rest = append(rest, c)
+			}
+		}
+	}
+	if len(first) == 0 {
+		return nil, errs.New(errs.Validation, "template %s is not available from registry %s", t.Name, registry)
+	}
+	return append(first, rest...), nil
+}
+
+// Failover fetches the first candidate that can be reached into `dst` with
+// `fetch`, and returns it. A network failure, or a template not embedded in
+// this build, moves on to the next candidate, any other error is returned
+// right away. Errors name the failed source.
+func Failover(ctx context.Context, candidates []Candidate, dst string, fetch func(ctx context.Context, src TemplateSource, dst string) error) (Candidate, error) {
+	var failures []string
+	for i, c := range candidates {
+		if i > 0 {
+			utility.Printf("trying %s (%s)", c.Source, c.Registry)
+		}
+		err := fetch(ctx, c.Source, dst)
+		if err == nil {
+			return c, nil
+		}
+		skippable := errs.KindOf(err) == errs.Network || errors.Is(err, ErrNotEmbedded)
+		if !skippable || len(candidates) == 1 {
+			return c, fmt.Errorf("%s failed: %w", c.Source, err)
+		}
+		utility.Warnf("%s (%s) failed: %s", c.Source, c.Registry, err)
+		failures = append(failures, fmt.Sprintf("%s (%s): %s", c.Source, c.Registry, err))
+		if err = os.RemoveAll(dst); err != nil {
+			return c, err
+		}
+	}
+	return Candidate{}, errs.New(errs.Network, "every source of the template failed:\n  %s", strings.Join(failures, "\n  "))
+}
+
+// Prober checks that the host of a remote URL answers before hb fetches from it,
+// so that an unreachable mirror fails fast instead of after a network timeout.
+type Prober interface {
+	Probe(ctx context.Context, rawURL string) error
+}
+
+// DialProber opens a TCP connection to the host of the URL, or to the HTTP
+// proxy of the URL if any. Local paths and SSH remotes always pass.
+type DialProber struct {
+	Timeout time.Duration
+}
+
+func (p DialProber) Probe(ctx context.Context, rawURL string) error {
+	addr, err := probeAddress(rawURL)
+	if err != nil || addr == "" {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return errs.Wrap(errs.Cancelled, err)
+		}
+		return errs.New(errs.Network, "%s is unreachable: %w", utility.Redact(rawURL), err)
+	}
+	return conn.Close()
+}
+
+// probeAddress returns the host:port to dial for `rawURL`, "" for local paths
+// and SSH remotes, whose host may be an alias of ~/.ssh/config.
+func probeAddress(rawURL string) (string, error) {
+	if isSSH(rawURL) {
+		return "", nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "", nil
+	}
+	ports := map[string]string{"http": "80", "https": "443", "git": "9418"}
+	port, ok := ports[u.Scheme]
+	if !ok {
+		return "", nil
+	}
+	if u.Scheme == "http" || u.Scheme == "https" {
+		proxy, err := http.ProxyFromEnvironment(&http.Request{URL: u})
+		if err != nil {
+			return "", errs.New(errs.Validation, "invalid proxy: %w", err)
+		}
+		if proxy != nil {
+			u = proxy
+			port = ports[u.Scheme]
+		}
+	}
+	if u.Port() != "" {
+		return u.Host, nil
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+var prober Prober = DialProber{Timeout: ProbeTimeout}
+
+// SetProber replaces the connectivity probe, e.g. by one always passing or
+// by one checking a local git daemon or HTTP stand-in.
+func SetProber(p Prober) {
+	prober = p
+}
+
+// probe checks `rawURL` with the connectivity probe.
+func probe(ctx context.Context, rawURL string) error {
+	if prober == nil {
+		return nil
+	}
+	return prober.Probe(ctx, rawURL)
+}
+
+// rewrites maps URL prefixes to their replacement, like insteadOf of git.
+var rewrites map[string]string
+
+// SetRewrites sets the URL rewrite rules: a URL starting with a key of
+// `rules` has this prefix replaced by its value, the longest key winning.
+func SetRewrites(rules map[string]string) {
+	rewrites = rules
+}
+
+// Rewrite returns `rawURL` rewritten by the rules of SetRewrites.
+func Rewrite(rawURL string) string {
+	prefixes := make([]string, 0, len(rewrites))
+	for prefix := range rewrites {
+		if strings.HasPrefix(rawURL, prefix) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	if len(prefixes) == 0 {
+		return rawURL
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) > len(prefixes[j])
+	})
+	rewritten := rewrites[prefixes[0]] + strings.TrimPrefix(rawURL, prefixes[0])
+	utility.Debugf("rewrite %s to %s", rawURL, rewritten)
+	return rewritten
+}
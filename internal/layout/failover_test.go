@@ -0,0 +1,241 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package layout
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+)
+
+func TestProbeAddress(t *testing.T) {
+	for _, env := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+		t.Setenv(env, "")
+	}
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://github.com/winc-link/hummingbird-mqtt-driver", "github.com:443"},
+		{"http://git.company.com/iot/driver.git", "git.company.com:80"},
+		{"https://git.company.com:8443/iot/driver.git", "git.company.com:8443"},
+		{"git://git.company.com/iot/driver.git", "git.company.com:9418"},
+		// SSH hosts may be aliases of ~/.ssh/config, git resolves them.
+		{"git@github-work:org/repo.git", ""},
+		{"ssh://git@git.company.com:2222/iot/driver.git", ""},
+		{"git+ssh://git@git.company.com/iot/driver.git", ""},
+		{"/home/alice/templates/driver", ""},
+		{"file:///home/alice/templates/driver.tar.gz", ""},
+	}
+	for _, tt := range tests {
+		got, err := probeAddress(tt.url)
+		if err != nil {
+			t.Errorf("probeAddress(%q) failed: %v", tt.url, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("probeAddress(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestDialProber(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	p := DialProber{Timeout: time.Second}
+	if err := p.Probe(context.Background(), srv.URL+"/driver.tar.gz"); err != nil {
+		t.Fatalf("probe of a listening stand-in failed: %v", err)
+	}
+
+	err := p.Probe(context.Background(), closedURL(t)+"/driver.tar.gz")
+	if errs.KindOf(err) != errs.Network {
+		t.Fatalf("probe of a closed port: got %v, want a network error", err)
+	}
+}
+
+func TestSetProberNil(t *testing.T) {
+	defer SetProber(prober)
+	SetProber(nil)
+	if err := probe(context.Background(), closedURL(t)); err != nil {
+		t.Fatalf("disabled probe failed: %v", err)
+	}
+}
+
+func TestFailover(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(templateArchive(t))
+	}))
+	defer srv.Close()
+	defer SetProber(prober)
+	SetProber(DialProber{Timeout: time.Second})
+
+	candidates := []Candidate{
+		{Registry: "Github", Source: &HTTPSource{URL: closedURL(t) + "/driver.tar.gz"}},
+		{Registry: "Gitee", Source: &HTTPSource{URL: srv.URL + "/driver.tar.gz"}},
+	}
+	dst := filepath.Join(t.TempDir(), "demo")
+	c, err := Failover(context.Background(), candidates, dst, fetchSource)
+	if err != nil {
+		t.Fatalf("Failover failed: %v", err)
+	}
+	if c.Registry != "Gitee" {
+		t.Errorf("fetched from %s, want Gitee", c.Registry)
+	}
+	if _, err = os.Stat(filepath.Join(dst, "go.mod")); err != nil {
+		t.Errorf("template not fetched: %v", err)
+	}
+}
+
+func TestFailoverAllUnreachable(t *testing.T) {
+	defer SetProber(prober)
+	SetProber(DialProber{Timeout: time.Second})
+
+	candidates := []Candidate{
+		{Registry: "Github", Source: &HTTPSource{URL: closedURL(t) + "/a.tar.gz"}},
+		{Registry: "Gitee", Source: &HTTPSource{URL: closedURL(t) + "/b.tar.gz"}},
+	}
+	_, err := Failover(context.Background(), candidates, filepath.Join(t.TempDir(), "demo"), fetchSource)
+	if errs.KindOf(err) != errs.Network {
+		t.Fatalf("got %v, want a network error", err)
+	}
+	for _, name := range []string{"a.tar.gz", "b.tar.gz"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("error %q does not name %s", err, name)
+		}
+	}
+}
+
+func TestFailoverSkipsMissingSnapshot(t *testing.T) {
+	defer SetProber(prober)
+	SetProber(DialProber{Timeout: time.Second})
+
+	candidates := []Candidate{
+		{Registry: "Github", Source: &HTTPSource{URL: closedURL(t) + "/a.tar.gz"}},
+		{Registry: EmbeddedRegistry, Source: &EmbeddedSource{Name: "hummingbird-missing-driver"}},
+	}
+	_, err := Failover(context.Background(), candidates, filepath.Join(t.TempDir(), "demo"), fetchSource)
+	if errs.KindOf(err) != errs.Network || !strings.Contains(err.Error(), "a.tar.gz") {
+		t.Fatalf("got %v, want the network error of a.tar.gz", err)
+	}
+}
+
+func TestCandidatesSkipMissingSnapshot(t *testing.T) {
+	tpl := Template{Name: "demo", Sources: []Location{
+		{Registry: "Github", URL: "https://github.com/demo/demo.git"},
+		{Registry: EmbeddedRegistry, URL: "embedded:hummingbird-missing-driver"},
+	}}
+	candidates, err := tpl.Candidates("Github", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range candidates {
+		if c.Registry == EmbeddedRegistry {
+			t.Errorf("got candidate %s, want no snapshot that is not in the build", c.Source)
+		}
+	}
+	if _, err = tpl.Candidates(EmbeddedRegistry, false); err != nil {
+		t.Errorf("choosing the embedded registry: %v", err)
+	}
+}
+
+func TestFailoverStopsOnOtherErrors(t *testing.T) {
+	tried := 0
+	fetch := func(ctx context.Context, src TemplateSource, dst string) error {
+		tried++
+		return errs.New(errs.Validation, "bad template")
+	}
+	candidates := []Candidate{
+		{Registry: "Github", Source: &HTTPSource{URL: "https://github.com/a.tar.gz"}},
+		{Registry: "Gitee", Source: &HTTPSource{URL: "https://gitee.com/a.tar.gz"}},
+	}
+	_, err := Failover(context.Background(), candidates, t.TempDir(), fetch)
+	if errs.KindOf(err) != errs.Validation || tried != 1 {
+		t.Fatalf("got %v after %d fetches, want the validation error of the first one", err, tried)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	defer SetRewrites(rewrites)
+	SetRewrites(map[string]string{
+		"https://github.com/":           "https://mirror.company.com/github/",
+		"https://github.com/winc-link/": "git@git.company.com:winc-link/",
+	})
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://github.com/winc-link/hummingbird-mqtt-driver", "git@git.company.com:winc-link/hummingbird-mqtt-driver"},
+		{"https://github.com/other/driver", "https://mirror.company.com/github/other/driver"},
+		{"https://gitee.com/winc-link/driver", "https://gitee.com/winc-link/driver"},
+	}
+	for _, tt := range tests {
+		if got := Rewrite(tt.url); got != tt.want {
+			t.Errorf("Rewrite(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func fetchSource(ctx context.Context, src TemplateSource, dst string) error {
+	return src.Fetch(ctx, dst)
+}
+
+// closedURL returns the URL of a local port nothing listens on.
+func closedURL(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	return "http://" + addr
+}
+
+// templateArchive returns a .tar.gz of a minimal template.
+func templateArchive(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	files := map[string]string{
+		"driver/go.mod":  "module driver\n\ngo 1.20\n",
+		"driver/main.go": "package main\n\nfunc main() {}\n",
+	}
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := errors.Join(tw.Close(), gz.Close()); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,160 @@
+//go:build ignore
+
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+// gen_templates packs the protocol templates of the built-in registry as
+// templates/<name>.tar.gz for `hb new --offline`, and writes embed_release.go,
+// which makes the release builds fail when a snapshot is missing. Run it with
+// `go generate ./internal/layout` or `make templates`.
+//
+// The templates are cloned from their Github source, or from
+// $TEMPLATE_REGISTRY/<name> when set.
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/winc-link/hummingbird-cli/internal/layout"
+)
+
+func main() {
+	names, err := snapshots()
+	if err == nil {
+		err = writeRelease(names)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gen_templates:", err)
+		os.Exit(1)
+	}
+}
+
+// snapshots packs every embedded template of the registry and returns their names.
+func snapshots() ([]string, error) {
+	if err := os.MkdirAll("templates", 0755); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, t := range layout.DefaultRegistry().Templates {
+		name, remote := "", ""
+		for _, s := range t.Sources {
+			switch {
+			case s.Registry == layout.EmbeddedRegistry:
+				name = strings.TrimPrefix(s.URL, "embedded:")
+			case s.Registry == "Github":
+				remote = s.URL
+			}
+		}
+		if name == "" {
+			continue
+		}
+		if base := os.Getenv("TEMPLATE_REGISTRY"); base != "" {
+			remote = strings.TrimSuffix(base, "/") + "/" + name
+		}
+		if remote == "" {
+			return nil, fmt.Errorf("template %s has no source to snapshot", t.Name)
+		}
+		if err := snapshot(name, remote); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// snapshot clones `remote` and packs it, without .git, as templates/<name>.tar.gz.
+func snapshot(name, remote string) error {
+	tmp, err := os.MkdirTemp("", "hb-snapshot-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	clone := exec.Command("git", "clone", "-q", "--depth", "1", remote, tmp)
+	clone.Stdout, clone.Stderr = os.Stdout, os.Stderr
+	if err = clone.Run(); err != nil {
+		return fmt.Errorf("git clone %s failed: %w", remote, err)
+	}
+
+	path := filepath.Join("templates", name+".tar.gz")
+	f, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path + ".tmp")
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	err = filepath.Walk(tmp, func(file string, info os.FileInfo, err error) error {
+		if err != nil || file == tmp {
+			return err
+		}
+		rel, err := filepath.Rel(tmp, file)
+		if err != nil {
+			return err
+		}
+		if rel == ".git" {
+			return filepath.SkipDir
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err = tw.WriteHeader(hdr); err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		src, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	for _, c := range []io.Closer{tw, gz, f} {
+		if cerr := c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("pack %s failed: %w", name, err)
+	}
+	fmt.Println("packed", path)
+	return os.Rename(path+".tmp", path)
+}
+
+// writeRelease writes embed_release.go, embedding every snapshot by name.
+func writeRelease(names []string) error {
+	var b strings.Builder
+	b.WriteString("// Code generated by gen_templates.go; DO NOT EDIT.\n\n//go:build release\n\npackage layout\n\nimport \"embed\"\n\n")
+	b.WriteString("// releaseSnapshots fails the release builds, built with -tags release,\n")
+	b.WriteString("// unless every template of the built-in registry is embedded.\n//\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "//go:embed templates/%s.tar.gz\n", name)
+	}
+	b.WriteString("var releaseSnapshots embed.FS\n")
+	return os.WriteFile("embed_release.go", []byte(b.String()), 0644)
+}
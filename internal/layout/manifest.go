@@ -0,0 +1,425 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package layout
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFile is the optional manifest at the root of a template. It is
+// removed from the generated project.
+const ManifestFile = "hb-template.yaml"
+
+// Variable types of a manifest.
+const (
+	TypeString = "string"
+	TypeBool   = "bool"
+	TypeChoice = "choice"
+	TypeMulti  = "multi"
+)
+
+// Manifest declares how a template is customized: the variables asked to the
+// user, the files rendered with text/template and the files only included on
+// some condition.
+//
+// Files ending with `.tmpl` and files matching a `render` pattern have their
+// content rendered; the `.tmpl` suffix is dropped. File and folder names
+// containing `{{` are always rendered. Besides the variables, templates can
+// use `.ProjectName` and `.Module`, and the functions `has`, `join`, `lower`
+// and `upper`.
+type Manifest struct {
+	Name        string       `yaml:"name,omitempty"`
+	Description string       `yaml:"description,omitempty"`
+	Variables   []*Variable  `yaml:"variables,omitempty"`
+	Render      []string     `yaml:"render,omitempty"`
+	Conditions  []*Condition `yaml:"conditions,omitempty"`
+	Hooks       Hooks        `yaml:"hooks,omitempty"`
+}
+
+// Hooks are shell commands run in the project folder while it is generated,
+// only with the consent of the user. They are rendered like the templates.
+type Hooks struct {
+	// PreGenerate run once the variables are answered, before the template is
+	// rendered and its module path rewritten.
+	PreGenerate []string `yaml:"pre_generate,omitempty"`
+	// PostGenerate run once the project is complete, after `go mod tidy`.
+	PostGenerate []string `yaml:"post_generate,omitempty"`
+}
+
+// Variable is a question asked when generating a project.
+type Variable struct {
+	Name    string      `yaml:"name"`
+	Type    string      `yaml:"type,omitempty"`
+	Prompt  string      `yaml:"prompt,omitempty"`
+	Help    string      `yaml:"help,omitempty"`
+	Default interface{} `yaml:"default,omitempty"`
+	// Choices are the options of choice and multi variables.
+	Choices  []string `yaml:"choices,omitempty"`
+	Required bool     `yaml:"required,omitempty"`
+	// Pattern is a regular expression string values must match.
+	Pattern string `yaml:"pattern,omitempty"`
+}
+
+// Condition includes the files matching Path, a slash separated glob relative
+// to the template root, only when the template pipeline When is true, e.g.
+// `.tls` or `has .features "docker"`. Matching a folder includes or excludes
+// all of its content.
+type Condition struct {
+	Path string `yaml:"path"`
+	When string `yaml:"when"`
+}
+
+// LoadManifest reads the manifest of the template in `dir`. It returns nil
+// without error when the template has none.
+func LoadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	if err = yaml.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parse %s failed: %w", ManifestFile, err)
+	}
+	if err = m.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", ManifestFile, err)
+	}
+	return m, nil
+}
+
+// Validate checks the manifest is consistent.
+func (m *Manifest) Validate() error {
+	names := map[string]bool{}
+	for _, v := range m.Variables {
+		if v.Name == "" {
+			return fmt.Errorf("variable without name")
+		}
+		if names[v.Name] {
+			return fmt.Errorf("variable %s declared twice", v.Name)
+		}
+		names[v.Name] = true
+		if v.Type == "" {
+			v.Type = TypeString
+		}
+		switch v.Type {
+		case TypeString, TypeBool:
+		case TypeChoice, TypeMulti:
+			if len(v.Choices) == 0 {
+				return fmt.Errorf("variable %s of type %s has no choices", v.Name, v.Type)
+			}
+		default:
+			return fmt.Errorf("variable %s has unknown type %s", v.Name, v.Type)
+		}
+		if v.Pattern != "" {
+			if _, err := regexp.Compile(v.Pattern); err != nil {
+				return fmt.Errorf("variable %s: invalid pattern: %w", v.Name, err)
+			}
+		}
+		if v.Default != nil {
+			def, err := v.Normalize(v.Default)
+			if err != nil {
+				return fmt.Errorf("variable %s: invalid default: %w", v.Name, err)
+			}
+			v.Default = def
+		}
+	}
+	for _, c := range m.Conditions {
+		if _, err := path.Match(c.Path, ""); err != nil {
+			return fmt.Errorf("condition %s: %w", c.Path, err)
+		}
+		if _, err := newTemplate(c.Path).Parse("{{if " + c.When + "}}{{end}}"); err != nil {
+			return fmt.Errorf("condition %s: %w", c.Path, err)
+		}
+	}
+	for _, r := range m.Render {
+		if _, err := path.Match(r, ""); err != nil {
+			return fmt.Errorf("render %s: %w", r, err)
+		}
+	}
+	for _, hooks := range [][]string{m.Hooks.PreGenerate, m.Hooks.PostGenerate} {
+		for _, h := range hooks {
+			if _, err := newTemplate("hook").Parse(h); err != nil {
+				return fmt.Errorf("hook %q: %w", h, err)
+			}
+		}
+	}
+	return nil
+}
+
+// RenderHooks returns the hook commands rendered with `data`.
+func (m *Manifest) RenderHooks(data map[string]interface{}) (Hooks, error) {
+	var hooks Hooks
+	for _, h := range m.Hooks.PreGenerate {
+		out, err := execute("hook", h, data)
+		if err != nil {
+			return hooks, err
+		}
+		hooks.PreGenerate = append(hooks.PreGenerate, string(out))
+	}
+	for _, h := range m.Hooks.PostGenerate {
+		out, err := execute("hook", h, data)
+		if err != nil {
+			return hooks, err
+		}
+		hooks.PostGenerate = append(hooks.PostGenerate, string(out))
+	}
+	return hooks, nil
+}
+
+// Variable returns the variable named `name`, or nil.
+func (m *Manifest) Variable(name string) *Variable {
+	for _, v := range m.Variables {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// Normalize converts `val`, as read from yaml, json or the command line, to
+// the go type of the variable, string, bool or []string, and validates it.
+func (v *Variable) Normalize(val interface{}) (interface{}, error) {
+	switch v.Type {
+	case TypeBool:
+		switch b := val.(type) {
+		case bool:
+			return b, nil
+		case string:
+			return strconv.ParseBool(b)
+		}
+		return nil, fmt.Errorf("%v is not a bool", val)
+	case TypeMulti:
+		var list []string
+		switch l := val.(type) {
+		case []string:
+			list = l
+		case []interface{}:
+			for _, item := range l {
+				list = append(list, fmt.Sprint(item))
+			}
+		case string:
+			list = splitList(l)
+		default:
+			return nil, fmt.Errorf("%v is not a list", val)
+		}
+		if v.Required && len(list) == 0 {
+			return nil, fmt.Errorf("select at least one of %s", strings.Join(v.Choices, ", "))
+		}
+		for _, item := range list {
+			if !contains(v.Choices, item) {
+				return nil, fmt.Errorf("%s is not one of %s", item, strings.Join(v.Choices, ", "))
+			}
+		}
+		return list, nil
+	}
+
+	s, ok := val.(string)
+	if !ok {
+		s = fmt.Sprint(val)
+	}
+	if v.Required && s == "" {
+		return nil, fmt.Errorf("value is required")
+	}
+	if v.Type == TypeChoice && !contains(v.Choices, s) {
+		return nil, fmt.Errorf("%s is not one of %s", s, strings.Join(v.Choices, ", "))
+	}
+	if v.Pattern != "" && !regexp.MustCompile(v.Pattern).MatchString(s) {
+		return nil, fmt.Errorf("%s does not match %s", s, v.Pattern)
+	}
+	return s, nil
+}
+
+// Apply customizes the template in `dir` in place: excluded files are
+// removed, then contents and names are rendered with `data`. The manifest
+// file is removed as well.
+func (m *Manifest) Apply(dir string, data map[string]interface{}) error {
+	var paths []string
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, p)
+		rel = filepath.ToSlash(rel)
+		if rel == ".git" {
+			return filepath.SkipDir
+		}
+		paths = append(paths, rel)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(filepath.Join(dir, ManifestFile)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	excluded := map[string]bool{}
+	var kept []string
+	for _, rel := range paths {
+		if rel == ManifestFile || excluded[path.Dir(rel)] {
+			excluded[rel] = true
+			continue
+		}
+		include, err := m.includes(rel, data)
+		if err != nil {
+			return err
+		}
+		if !include {
+			excluded[rel] = true
+			if err = os.RemoveAll(filepath.Join(dir, filepath.FromSlash(rel))); err != nil {
+				return err
+			}
+			continue
+		}
+		kept = append(kept, rel)
+	}
+
+	for _, rel := range kept {
+		file := filepath.Join(dir, filepath.FromSlash(rel))
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !m.renders(rel) {
+			continue
+		}
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		out, err := execute(rel, string(content), data)
+		if err != nil {
+			return err
+		}
+		if err = os.WriteFile(file, out, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+
+	// Rename the deepest paths first so their parents are still at the template path.
+	sort.Slice(kept, func(i, j int) bool {
+		return strings.Count(kept[i], "/") > strings.Count(kept[j], "/")
+	})
+	for _, rel := range kept {
+		base := path.Base(rel)
+		name := strings.TrimSuffix(base, ".tmpl")
+		if strings.Contains(name, "{{") {
+			out, err := execute(rel, name, data)
+			if err != nil {
+				return err
+			}
+			name = string(out)
+		}
+		if name == base {
+			continue
+		}
+		if name == "" || strings.ContainsAny(name, `/\`) {
+			return fmt.Errorf("%s renders to invalid name %q", rel, name)
+		}
+		old := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.Rename(old, filepath.Join(filepath.Dir(old), name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// includes evaluates the conditions matching `rel`.
+func (m *Manifest) includes(rel string, data map[string]interface{}) (bool, error) {
+	for _, c := range m.Conditions {
+		if ok, _ := path.Match(c.Path, rel); !ok {
+			continue
+		}
+		out, err := execute(c.Path, "{{if "+c.When+"}}true{{end}}", data)
+		if err != nil {
+			return false, err
+		}
+		if string(out) != "true" {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// renders reports whether the content of file `rel` is a text/template.
+func (m *Manifest) renders(rel string) bool {
+	if strings.HasSuffix(rel, ".tmpl") {
+		return true
+	}
+	for _, pattern := range m.Render {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func newTemplate(name string) *template.Template {
+	return template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+		"has": func(list []string, item string) bool {
+			return contains(list, item)
+		},
+		"join":  strings.Join,
+		"lower": strings.ToLower,
+		"upper": strings.ToUpper,
+	})
+}
+
+func execute(name, text string, data map[string]interface{}) ([]byte, error) {
+	t, err := newTemplate(name).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func contains(list []string, item string) bool {
+	for _, v := range list {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
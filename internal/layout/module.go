@@ -0,0 +1,170 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package layout
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+	"github.com/winc-link/hummingbird-cli/utility"
+	"golang.org/x/mod/modfile"
+)
+
+// ModulePath returns the module path declared by `dir/go.mod`.
+func ModulePath(dir string) (string, error) {
+	path := filepath.Join(dir, "go.mod")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	module := modfile.ModulePath(data)
+	if module == "" {
+		return "", errs.New(errs.Validation, "%s does not declare a module path", path)
+	}
+	return module, nil
+}
+
+// RewriteModule renames module `from` to `to` in the project `dir`: the module
+// statement of go.mod and every import path of `from` or its packages in the
+// go files. String literals, comments and modules merely sharing the prefix,
+// e.g. `driver-sdk` for module `driver`, are left untouched.
+// It returns the files changed, relative to `dir`.
+func RewriteModule(dir, from, to string) ([]string, error) {
+	var changed []string
+	gomod := filepath.Join(dir, "go.mod")
+	data, err := os.ReadFile(gomod)
+	if err != nil {
+		return nil, err
+	}
+	newData, err := RewriteGoMod(gomod, data, to)
+	if err != nil {
+		return nil, err
+	}
+	if string(newData) != string(data) {
+		if err = os.WriteFile(gomod, newData, 0644); err != nil {
+			return nil, err
+		}
+		changed = append(changed, "go.mod")
+	}
+
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// The go tool ignores testdata, its go files may not even parse.
+			if name := info.Name(); path != dir && (name == ".git" || name == "vendor" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) != ".go" {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		newData, err := RewriteImports(path, data, from, to)
+		if err != nil {
+			utility.Warnf("imports left unchanged: %s", err)
+			return nil
+		}
+		if string(newData) == string(data) {
+			return nil
+		}
+		if err = os.WriteFile(path, newData, info.Mode().Perm()); err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		changed = append(changed, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// go.mod first, then the go files in lexical order.
+	if len(changed) > 0 && changed[0] == "go.mod" {
+		sort.Strings(changed[1:])
+	} else {
+		sort.Strings(changed)
+	}
+	return changed, nil
+}
+
+// RewriteGoMod returns the go.mod content `data` with its module path set to `module`.
+func RewriteGoMod(name string, data []byte, module string) ([]byte, error) {
+	f, err := modfile.Parse(name, data, nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.Module != nil && f.Module.Mod.Path == module {
+		return data, nil
+	}
+	if err = f.AddModuleStmt(module); err != nil {
+		return nil, err
+	}
+	return f.Format()
+}
+
+// RewriteImports returns the go source `data` with the import paths of module
+// `from` moved to module `to`. Only the import path literals are replaced, so
+// the rest of the file keeps its exact formatting.
+func RewriteImports(name string, data []byte, from, to string) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, name, data, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	type edit struct {
+		start, end int
+		text       string
+	}
+	var edits []edit
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+		rest, ok := strings.CutPrefix(path, from)
+		if !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+			continue
+		}
+		edits = append(edits, edit{
+			start: fset.Position(spec.Path.Pos()).Offset,
+			end:   fset.Position(spec.Path.End()).Offset,
+			text:  strconv.Quote(to + rest),
+		})
+	}
+	if len(edits) == 0 {
+		return data, nil
+	}
+
+	out := make([]byte, 0, len(data))
+	last := 0
+	for _, e := range edits {
+		out = append(out, data[last:e.start]...)
+		out = append(out, e.text...)
+		last = e.end
+	}
+	return append(out, data[last:]...), nil
+}
@@ -0,0 +1,155 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package layout
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRewriteImports(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "module and packages",
+			src:  "package main\n\nimport (\n\t\"driver\"\n\t\"driver/internal/server\"\n)\n",
+			want: "package main\n\nimport (\n\t\"example.com/demo\"\n\t\"example.com/demo/internal/server\"\n)\n",
+		},
+		{
+			name: "named import",
+			src:  "package main\n\nimport srv \"driver/internal/server\"\n",
+			want: "package main\n\nimport srv \"example.com/demo/internal/server\"\n",
+		},
+		{
+			name: "module sharing the prefix",
+			src:  "package main\n\nimport (\n\t\"driver-sdk/pkg\"\n\t\"driverx\"\n)\n",
+			want: "package main\n\nimport (\n\t\"driver-sdk/pkg\"\n\t\"driverx\"\n)\n",
+		},
+		{
+			name: "string literals and comments",
+			src:  "package main\n\nimport \"driver/config\"\n\n// driver/config is loaded from \"driver/config\".\nconst path = \"driver/config\"\n",
+			want: "package main\n\nimport \"example.com/demo/config\"\n\n// driver/config is loaded from \"driver/config\".\nconst path = \"driver/config\"\n",
+		},
+		{
+			name: "formatting kept",
+			src:  "package main\n\nimport (\n\t\"fmt\"   // printing\n\n\t_ \"driver/plugins\" // register\n)\n",
+			want: "package main\n\nimport (\n\t\"fmt\"   // printing\n\n\t_ \"example.com/demo/plugins\" // register\n)\n",
+		},
+		{
+			name: "no import",
+			src:  "package main\n\nvar s = \"driver\"\n",
+			want: "package main\n\nvar s = \"driver\"\n",
+		},
+	}
+	for _, tt := range tests {
+		got, err := RewriteImports("main.go", []byte(tt.src), "driver", "example.com/demo")
+		if err != nil {
+			t.Errorf("%s: RewriteImports failed: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: RewriteImports =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRewriteGoMod(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "plain",
+			src:  "module driver\n\ngo 1.20\n",
+			want: "module example.com/demo\n\ngo 1.20\n",
+		},
+		{
+			name: "comments before the module line",
+			src:  "// Hummingbird driver template.\n// module driver is replaced by hb new.\nmodule driver\n\ngo 1.20\n\nrequire driver-sdk v1.0.0\n",
+			want: "// Hummingbird driver template.\n// module driver is replaced by hb new.\nmodule example.com/demo\n\ngo 1.20\n\nrequire driver-sdk v1.0.0\n",
+		},
+		{
+			name: "quoted module path",
+			src:  "module \"driver\"\n\ngo 1.20\n",
+			want: "module example.com/demo\n\ngo 1.20\n",
+		},
+		{
+			name: "already renamed",
+			src:  "module example.com/demo // generated\n",
+			want: "module example.com/demo // generated\n",
+		},
+	}
+	for _, tt := range tests {
+		got, err := RewriteGoMod("go.mod", []byte(tt.src), "example.com/demo")
+		if err != nil {
+			t.Errorf("%s: RewriteGoMod failed: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: RewriteGoMod =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRewriteModule(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                     "module driver\n\ngo 1.20\n",
+		"main.go":                    "package main\n\nimport _ \"driver/internal\"\n",
+		"internal/x.go":              "package internal\n\nconst Name = \"driver\"\n",
+		"vendor/driver/lib/lib.go":   "package lib\n\nimport _ \"driver/internal\"\n",
+		"cmd/tool/main.go":           "package main\n\nimport \"driver/internal\"\n\nvar _ = internal.Name\n",
+		"testdata/broken.txt":        "import \"driver\"\n",
+		"testdata/broken.go":         "package broken\n\nimport \"driver\n",
+		"internal/broken.go":         "package internal\n\nimport (\n\t\"driver\"\n",
+		"internal/sdk/client/sdk.go": "package client\n\nimport _ \"driver-sdk\"\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changed, err := RewriteModule(dir, "driver", "example.com/demo")
+	if err != nil {
+		t.Fatalf("RewriteModule failed: %v", err)
+	}
+	want := []string{"go.mod", "cmd/tool/main.go", "main.go"}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed %v, want %v", changed, want)
+	}
+	vendored, _ := os.ReadFile(filepath.Join(dir, "vendor/driver/lib/lib.go"))
+	if string(vendored) != files["vendor/driver/lib/lib.go"] {
+		t.Errorf("vendored file rewritten:\n%s", vendored)
+	}
+	for _, name := range []string{"testdata/broken.go", "internal/broken.go"} {
+		if data, _ := os.ReadFile(filepath.Join(dir, name)); string(data) != files[name] {
+			t.Errorf("%s rewritten:\n%s", name, data)
+		}
+	}
+	if module, _ := ModulePath(dir); module != "example.com/demo" {
+		t.Errorf("module %q, want example.com/demo", module)
+	}
+}
@@ -0,0 +1,210 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package layout
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectRegistryFile is the project level registry, relative to the working directory.
+// Its templates are added to, or replace by name, the ones of the user registry.
+const ProjectRegistryFile = ".hb/templates.yaml"
+
+//go:embed default_templates.yaml
+var defaultRegistry []byte
+
+// Registry is the list of templates offered by `hb new`.
+type Registry struct {
+	Templates []*Template `yaml:"templates"`
+}
+
+// Template is one entry of the registry.
+type Template struct {
+	Name        string     `yaml:"name"`
+	Description string     `yaml:"description,omitempty"`
+	Protocols   []string   `yaml:"protocols,omitempty"`
+	Ref         string     `yaml:"ref,omitempty"`
+	Sources     []Location `yaml:"sources"`
+}
+
+// Location is where a template can be fetched from for one registry.
+// URL and Mirrors are any spec accepted by ParseSource; the mirrors are
+// tried in order when URL cannot be reached.
+type Location struct {
+	Registry string   `yaml:"registry"`
+	URL      string   `yaml:"url"`
+	Mirrors  []string `yaml:"mirrors,omitempty"`
+}
+
+// userRegistryFile replaces the user registry when set.
+var userRegistryFile string
+
+// SetUserRegistryFile makes `path` the user registry, e.g. the template set
+// of a profile. An empty path restores `~/.hb/templates.yaml`.
+func SetUserRegistryFile(path string) {
+	userRegistryFile = path
+}
+
+// UserRegistryFile returns the path of the user registry, `~/.hb/templates.yaml`
+// unless replaced by SetUserRegistryFile.
+func UserRegistryFile() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	if userRegistryFile == "~" || strings.HasPrefix(userRegistryFile, "~/") {
+		return filepath.Join(home, userRegistryFile[1:]), nil
+	}
+	if userRegistryFile != "" {
+		return userRegistryFile, nil
+	}
+	return filepath.Join(home, ".hb", "templates.yaml"), nil
+}
+
+// DefaultRegistry returns the registry compiled into hb.
+func DefaultRegistry() *Registry {
+	r := &Registry{}
+	if err := yaml.Unmarshal(defaultRegistry, r); err != nil {
+		panic(fmt.Sprintf("parse default template registry failed: %v", err))
+	}
+	return r
+}
+
+// LoadRegistry returns the effective registry: the user registry, or the
+// built-in one when it does not exist, overlaid with the project registry.
+func LoadRegistry() (*Registry, error) {
+	path, err := UserRegistryFile()
+	if err != nil {
+		return nil, err
+	}
+	r, err := ReadRegistry(path, true)
+	if err != nil {
+		return nil, err
+	}
+	project, err := ReadRegistry(ProjectRegistryFile, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range project.Templates {
+		r.Put(t)
+	}
+	return r, nil
+}
+
+// ReadRegistry reads the registry file `path`. If it does not exist, the built-in
+// registry is returned when `withDefault` is true, or an empty one otherwise.
+func ReadRegistry(path string, withDefault bool) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if withDefault {
+			return DefaultRegistry(), nil
+		}
+		return &Registry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := &Registry{}
+	if err = yaml.Unmarshal(data, r); err != nil {
+		return nil, errs.New(errs.Validation, "parse template registry %s failed: %w", path, err)
+	}
+	for _, t := range r.Templates {
+		if t.Name == "" {
+			return nil, errs.New(errs.Validation, "template registry %s: template without name", path)
+		}
+		if len(t.Sources) == 0 {
+			return nil, errs.New(errs.Validation, "template registry %s: template %s has no sources", path, t.Name)
+		}
+	}
+	return r, nil
+}
+
+// Save writes the registry to `path`.
+func (r *Registry) Save(path string) error {
+	data, err := MarshalYAML(r)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Get returns the template named `name`, compared case-insensitively, or nil.
+func (r *Registry) Get(name string) *Template {
+	for _, t := range r.Templates {
+		if strings.EqualFold(t.Name, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+// Put adds `t`, replacing the template with the same name if any.
+func (r *Registry) Put(t *Template) {
+	for i, old := range r.Templates {
+		if strings.EqualFold(old.Name, t.Name) {
+			r.Templates[i] = t
+			return
+		}
+	}
+	r.Templates = append(r.Templates, t)
+}
+
+// Remove deletes the template named `name` and reports whether it existed.
+func (r *Registry) Remove(name string) bool {
+	for i, t := range r.Templates {
+		if strings.EqualFold(t.Name, name) {
+			r.Templates = append(r.Templates[:i], r.Templates[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Registries returns the registry names the template is available from, in order.
+func (t *Template) Registries() []string {
+	names := make([]string, 0, len(t.Sources))
+	for _, s := range t.Sources {
+		names = append(names, s.Registry)
+	}
+	return names
+}
+
+// Source returns the TemplateSource of the template for `registry`.
+func (t *Template) Source(registry string) (TemplateSource, error) {
+	for _, s := range t.Sources {
+		if !strings.EqualFold(s.Registry, registry) {
+			continue
+		}
+		src, err := ParseSource(s.URL)
+		if err != nil {
+			return nil, err
+		}
+		if g, ok := src.(*GitSource); ok && g.Ref == "" {
+			g.Ref = t.Ref
+		}
+		return src, nil
+	}
+	return nil, errs.New(errs.Validation, "template %s is not available from registry %s", t.Name, registry)
+}
@@ -0,0 +1,409 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package layout
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+	"github.com/winc-link/hummingbird-cli/internal/executor"
+)
+
+// TemplateSource fetches a driver template into a local directory.
+type TemplateSource interface {
+	// Fetch writes the template files into `dst`, which must not exist yet.
+	Fetch(ctx context.Context, dst string) error
+	// String describes the source in the command output.
+	String() string
+	// Spec returns the spec ParseSource turns back into this source.
+	Spec() string
+	// Revision identifies exactly what the last Fetch got, e.g. the commit of
+	// a git source or the digest of an archive. It is "" for local folders.
+	Revision() string
+}
+
+// GitSource clones a git repository, optionally at branch, tag or commit Ref.
+// The credentials of an HTTP(S) URL are only used to fetch, never shown.
+type GitSource struct {
+	URL    string
+	Ref    string
+	commit string
+}
+
+func (s *GitSource) Fetch(ctx context.Context, dst string) error {
+	remote, user := remoteURL(s.URL)
+	if err := probe(ctx, remote); err != nil {
+		return err
+	}
+	if _, err := remoteGit(ctx, "", remote, user, "clone", "--progress", remote, dst); err != nil {
+		return errs.Wrap(errs.Network, err)
+	}
+	if s.Ref != "" {
+		if _, err := git(ctx, dst, "checkout", "-q", s.Ref); err != nil {
+			return errs.Wrap(errs.Validation, err)
+		}
+	}
+	out, err := git(ctx, dst, "rev-parse", "HEAD")
+	if err != nil {
+		return fmt.Errorf("resolve commit of %s failed: %w", s.URL, err)
+	}
+	s.commit = strings.TrimSpace(string(out))
+	return nil
+}
+
+func (s *GitSource) String() string {
+	clean, _ := splitCredentials(s.URL)
+	if s.Ref != "" {
+		return "git clone " + clean + " (" + s.Ref + ")"
+	}
+	return "git clone " + clean
+}
+
+func (s *GitSource) Spec() string {
+	clean, _ := splitCredentials(s.URL)
+	return "git+" + clean
+}
+
+func (s *GitSource) Revision() string {
+	return s.commit
+}
+
+// remoteURL returns the URL to fetch `raw` from: rewritten and without its
+// credentials, returned apart unless the rewrite changed the host.
+func remoteURL(raw string) (string, *url.Userinfo) {
+	clean, user := splitCredentials(raw)
+	remote := Rewrite(clean)
+	if user != nil {
+		before, _ := url.Parse(clean)
+		after, err := url.Parse(remote)
+		if err != nil || after.Host != before.Host {
+			user = nil
+		}
+	}
+	return remote, user
+}
+
+// git runs a git command in `dir` and returns its output. The commands
+// talking to a remote get a longer timeout and show their progress.
+func git(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	return runGit(ctx, dir, nil, args...)
+}
+
+// remoteGit runs a git command talking to `remote` with its credentials.
+func remoteGit(ctx context.Context, dir, remote string, user *url.Userinfo, args ...string) ([]byte, error) {
+	return runGit(ctx, dir, gitEnv(remote, user), args...)
+}
+
+func runGit(ctx context.Context, dir string, env []string, args ...string) ([]byte, error) {
+	opts := executor.Options{Dir: dir, Env: env, Timeout: executor.LocalTimeout}
+	switch args[0] {
+	case "clone", "fetch", "remote":
+		opts.Timeout = executor.NetworkTimeout
+		opts.TimeoutKind = errs.Network
+		opts.Stream = true
+	}
+	return executor.Run(ctx, opts, "git", args...)
+}
+
+// DirSource copies a template from a local directory, skipping its `.git` folder.
+type DirSource struct {
+	Path string
+}
+
+func (s *DirSource) Fetch(ctx context.Context, dst string) error {
+	return CopyDir(s.Path, dst, func(rel string) bool {
+		return rel == ".git"
+	})
+}
+
+func (s *DirSource) String() string {
+	return "copy " + s.Path
+}
+
+func (s *DirSource) Spec() string {
+	return absPath(s.Path)
+}
+
+func (s *DirSource) Revision() string {
+	return ""
+}
+
+// ArchiveSource extracts a local `.tar.gz`, `.tgz` or `.zip` archive.
+type ArchiveSource struct {
+	Path   string
+	digest string
+}
+
+func (s *ArchiveSource) Fetch(ctx context.Context, dst string) error {
+	digest, err := fileDigest(s.Path)
+	if err != nil {
+		return err
+	}
+	s.digest = digest
+	return extractArchive(s.Path, dst)
+}
+
+func (s *ArchiveSource) String() string {
+	return "extract " + s.Path
+}
+
+func (s *ArchiveSource) Spec() string {
+	return absPath(s.Path)
+}
+
+func (s *ArchiveSource) Revision() string {
+	return s.digest
+}
+
+// HTTPSource downloads a `.tar.gz`, `.tgz` or `.zip` archive over HTTP(S).
+type HTTPSource struct {
+	URL    string
+	digest string
+}
+
+func (s *HTTPSource) Fetch(ctx context.Context, dst string) error {
+	remote, user := remoteURL(s.URL)
+	if err := probe(ctx, remote); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, executor.NetworkTimeout)
+	defer cancel()
+	resp, err := get(ctx, remote, user)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errs.New(errs.Network, "download %s failed: %s", remote, resp.Status)
+	}
+
+	tmp, err := os.CreateTemp("", "hb-template-*"+archiveExt(s.URL))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		return errs.Wrap(errs.Network, err)
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if s.digest, err = fileDigest(tmp.Name()); err != nil {
+		return err
+	}
+	return extractArchive(tmp.Name(), dst)
+}
+
+func (s *HTTPSource) String() string {
+	return "download " + s.Spec()
+}
+
+func (s *HTTPSource) Spec() string {
+	clean, _ := splitCredentials(s.URL)
+	return clean
+}
+
+func (s *HTTPSource) Revision() string {
+	return s.digest
+}
+
+// EmbeddedSource extracts a template snapshot bundled into the binary.
+type EmbeddedSource struct {
+	Name   string
+	digest string
+}
+
+func (s *EmbeddedSource) Fetch(ctx context.Context, dst string) error {
+	digest, err := embeddedDigest(s.Name)
+	if err != nil {
+		return err
+	}
+	s.digest = digest
+	return ExtractEmbedded(s.Name, dst)
+}
+
+func (s *EmbeddedSource) String() string {
+	return "extract embedded template " + s.Name
+}
+
+func (s *EmbeddedSource) Spec() string {
+	return "embedded:" + s.Name
+}
+
+func (s *EmbeddedSource) Revision() string {
+	return s.digest
+}
+
+// ParseSource parses a `--from` spec into a TemplateSource. Supported forms:
+//
+//	embedded:<name>                         template bundled into hb
+//	git+<url>, *.git, git@host:org/repo     git repository
+//	http(s)://host/path.tar.gz|.tgz|.zip    archive downloaded over HTTP(S)
+//	http(s)://host/org/repo                 git repository
+//	file://<path>, <path>                   local directory or archive
+func ParseSource(spec string) (TemplateSource, error) {
+	switch {
+	case spec == "":
+		return nil, errs.New(errs.Validation, "empty template source")
+	case strings.HasPrefix(spec, "embedded:"):
+		return &EmbeddedSource{Name: strings.TrimPrefix(spec, "embedded:")}, nil
+	case strings.HasPrefix(spec, "git+"):
+		return &GitSource{URL: strings.TrimPrefix(spec, "git+")}, nil
+	case strings.HasPrefix(spec, "http://"), strings.HasPrefix(spec, "https://"):
+		if archiveExt(spec) != "" {
+			return &HTTPSource{URL: spec}, nil
+		}
+		return &GitSource{URL: spec}, nil
+	case strings.HasPrefix(spec, "ssh://"), strings.HasPrefix(spec, "git://"),
+		strings.HasPrefix(spec, "git@"), strings.HasSuffix(spec, ".git"):
+		return &GitSource{URL: spec}, nil
+	}
+
+	path := strings.TrimPrefix(spec, "file://")
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, errs.New(errs.Validation, "unknown template source %s: %w", spec, err)
+	}
+	if stat.IsDir() {
+		return &DirSource{Path: path}, nil
+	}
+	if archiveExt(path) == "" {
+		return nil, errs.New(errs.Validation, "unsupported template archive %s, expect .tar.gz, .tgz or .zip", path)
+	}
+	return &ArchiveSource{Path: path}, nil
+}
+
+// get sends a GET request for `remote` bound to `ctx`, with the credentials
+// of the URL or of its host. When the server asks for credentials hb does
+// not know, they are asked to the credential helpers of git.
+func get(ctx context.Context, remote string, embedded *url.Userinfo) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, remote, nil)
+	if err != nil {
+		return nil, errs.Wrap(errs.Validation, err)
+	}
+	user, password, auth := basicAuth(remote, embedded)
+	if auth {
+		req.SetBasicAuth(user, password)
+	}
+	resp, err := send(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || auth {
+		return resp, err
+	}
+	if user, password, auth = gitCredential(ctx, "fill", req.URL, "", ""); !auth {
+		return resp, nil
+	}
+	resp.Body.Close()
+	req.SetBasicAuth(user, password)
+	if resp, err = send(req); err != nil {
+		return nil, err
+	}
+	action := "approve"
+	if resp.StatusCode == http.StatusUnauthorized {
+		action = "reject"
+	}
+	gitCredential(ctx, action, req.URL, user, password)
+	return resp, nil
+}
+
+func send(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if errors.Is(err, context.Canceled) {
+		return nil, errs.Wrap(errs.Cancelled, err)
+	}
+	return resp, errs.Wrap(errs.Network, err)
+}
+
+// archiveExt returns the archive extension of `name`, or "" if it is not a supported archive.
+func archiveExt(name string) string {
+	if i := strings.IndexAny(name, "?#"); i != -1 {
+		name = name[:i]
+	}
+	lower := strings.ToLower(name)
+	for _, ext := range []string{".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(lower, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// fileDigest returns the sha256 digest of file `path`, as "sha256:<hex>".
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return digest(f)
+}
+
+func digest(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// CopyDir copies the tree `src` into `dst`, skipping every path for which
+// `skip`, called with slash separated relative paths, returns true.
+func CopyDir(src, dst string, skip func(rel string) bool) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel != "." && skip != nil && skip(filepath.ToSlash(rel)) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return writeFile(target, f, info.Mode().Perm())
+	})
+}
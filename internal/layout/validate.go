@@ -0,0 +1,367 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package layout
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	"github.com/winc-link/hummingbird-cli/internal/executor"
+	"golang.org/x/mod/modfile"
+)
+
+// Severities of a Finding.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Finding is a problem found by Validate.
+type Finding struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (f Finding) String() string {
+	pos := f.File
+	if f.Line > 0 {
+		pos += ":" + strconv.Itoa(f.Line)
+	}
+	if pos != "" {
+		pos += ": "
+	}
+	return fmt.Sprintf("%-7s [%s] %s%s", f.Severity, f.Check, pos, f.Message)
+}
+
+// builtinVariables are provided to every template besides the manifest variables.
+var builtinVariables = map[string]bool{"ProjectName": true, "Module": true}
+
+// Validate checks that the template in `dir` survives `hb new`: its go.mod is
+// valid, its module path is only used by imports, which are the only places
+// rewritten, and its manifest variables are declared and used.
+func Validate(dir string) []Finding {
+	var findings []Finding
+	add := func(check, severity, file string, line int, format string, args ...interface{}) {
+		findings = append(findings, Finding{check, severity, file, line, fmt.Sprintf(format, args...)})
+	}
+
+	module := ""
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		add("go-mod", SeverityError, "go.mod", 0, "%s", err)
+	} else if f, err := modfile.Parse("go.mod", data, nil); err != nil {
+		add("go-mod", SeverityError, "go.mod", 0, "%s", err)
+	} else if f.Module == nil {
+		add("go-mod", SeverityError, "go.mod", 0, "no module statement")
+	} else {
+		module = f.Module.Mod.Path
+	}
+
+	m, err := LoadManifest(dir)
+	if err != nil {
+		add("manifest", SeverityError, ManifestFile, 0, "%s", err)
+	}
+	if m == nil {
+		m = &Manifest{}
+	}
+	used := map[string]bool{}
+
+	err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		rel = filepath.ToSlash(rel)
+		if info.IsDir() {
+			if rel == ".git" {
+				return filepath.SkipDir
+			}
+		}
+		if rel == "." || rel == ManifestFile {
+			return nil
+		}
+		if strings.Contains(path.Base(rel), "{{") {
+			if err := collectFields(path.Base(rel), used); err != nil {
+				add("template", SeverityError, rel, 0, "file name: %s", err)
+			}
+		}
+		if info.IsDir() || !info.Mode().IsRegular() {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if m.renders(rel) {
+			if err := collectFields(string(content), used); err != nil {
+				add("template", SeverityError, rel, 0, "%s", err)
+			}
+		}
+		if module == "" {
+			return nil
+		}
+		if strings.HasSuffix(rel, ".go") && !m.renders(rel) {
+			for _, f := range moduleUsages(rel, content, module) {
+				findings = append(findings, f)
+			}
+		} else if rel != "go.mod" && rel != "go.sum" && containsPath(string(content), module) {
+			add("module-path", SeverityWarning, rel, lineOf(content, module), "module path %s is not rewritten outside go files", module)
+		}
+		return nil
+	})
+	if err != nil {
+		add("walk", SeverityError, "", 0, "%s", err)
+	}
+
+	for _, c := range m.Conditions {
+		if err := collectFields("{{if "+c.When+"}}{{end}}", used); err != nil {
+			add("manifest", SeverityError, ManifestFile, 0, "condition %s: %s", c.Path, err)
+		}
+	}
+	for _, hooks := range [][]string{m.Hooks.PreGenerate, m.Hooks.PostGenerate} {
+		for _, h := range hooks {
+			if err := collectFields(h, used); err != nil {
+				add("manifest", SeverityError, ManifestFile, 0, "hook %q: %s", h, err)
+			}
+		}
+	}
+	for _, v := range m.Variables {
+		if !used[v.Name] {
+			add("variables", SeverityWarning, ManifestFile, 0, "variable %s is never used", v.Name)
+		}
+	}
+	var undefined []string
+	for name := range used {
+		if m.Variable(name) == nil && !builtinVariables[name] {
+			undefined = append(undefined, name)
+		}
+	}
+	sort.Strings(undefined)
+	for _, name := range undefined {
+		add("variables", SeverityError, ManifestFile, 0, "variable %s is used but not declared", name)
+	}
+	return findings
+}
+
+// TrialRender generates the template in `dir` into `dst` the way `hb new`
+// does, with sample answers and module path `module`, then runs `go vet`.
+func TrialRender(ctx context.Context, dir, dst, module string) []Finding {
+	fail := func(check, format string, args ...interface{}) []Finding {
+		return []Finding{{Check: check, Severity: SeverityError, Message: fmt.Sprintf(format, args...)}}
+	}
+	if err := CopyDir(dir, dst, func(rel string) bool { return rel == ".git" }); err != nil {
+		return fail("render", "%s", err)
+	}
+	m, err := LoadManifest(dst)
+	if err != nil {
+		return fail("render", "%s", err)
+	}
+	if m != nil {
+		data := map[string]interface{}{"ProjectName": path.Base(module), "Module": module}
+		for _, v := range m.Variables {
+			data[v.Name] = sampleValue(v)
+		}
+		if err = m.Apply(dst, data); err != nil {
+			return fail("render", "%s", err)
+		}
+	}
+	from, err := ModulePath(dst)
+	if err != nil {
+		return fail("render", "%s", err)
+	}
+	if _, err = RewriteModule(dst, from, module); err != nil {
+		return fail("render", "%s", err)
+	}
+
+	opts := executor.Options{Dir: dst, Env: []string{"GOFLAGS=-mod=mod"}, Timeout: executor.NetworkTimeout}
+	if _, err = executor.Run(ctx, opts, "go", "vet", "./..."); err != nil {
+		return fail("go-vet", "%s", err)
+	}
+	return nil
+}
+
+// sampleValue returns the answer used for `v` by a trial render, the default
+// or a value including as many files as possible.
+func sampleValue(v *Variable) interface{} {
+	if v.Default != nil {
+		return v.Default
+	}
+	switch v.Type {
+	case TypeBool:
+		return true
+	case TypeChoice:
+		return v.Choices[0]
+	case TypeMulti:
+		return v.Choices
+	}
+	return "example"
+}
+
+// moduleUsages reports the string literals and comments of a go file
+// containing `module`, which the import rewriting leaves untouched.
+func moduleUsages(rel string, content []byte, module string) []Finding {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, rel, content, parser.ParseComments)
+	if err != nil {
+		return []Finding{{Check: "go-syntax", Severity: SeverityError, File: rel, Message: err.Error()}}
+	}
+	imports := map[*ast.BasicLit]bool{}
+	for _, spec := range f.Imports {
+		imports[spec.Path] = true
+	}
+	var findings []Finding
+	ast.Inspect(f, func(n ast.Node) bool {
+		lit, ok := n.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING || imports[lit] {
+			return true
+		}
+		if containsPath(lit.Value, module) {
+			findings = append(findings, Finding{
+				Check:    "module-path",
+				Severity: SeverityError,
+				File:     rel,
+				Line:     fset.Position(lit.Pos()).Line,
+				Message:  fmt.Sprintf("module path %s in a string literal is not rewritten", module),
+			})
+		}
+		return true
+	})
+	for _, group := range f.Comments {
+		for _, c := range group.List {
+			if containsPath(c.Text, module) {
+				findings = append(findings, Finding{
+					Check:    "module-path",
+					Severity: SeverityWarning,
+					File:     rel,
+					Line:     fset.Position(c.Pos()).Line,
+					Message:  fmt.Sprintf("module path %s in a comment is not rewritten", module),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// containsPath reports whether `s` contains `module` as a whole path, not as
+// the prefix of a longer name like `driver-sdk` for `driver`.
+func containsPath(s, module string) bool {
+	for i := 0; ; {
+		j := strings.Index(s[i:], module)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(module)
+		if (start == 0 || !isPathChar(s[start-1])) && (end == len(s) || !isPathChar(s[end]) || s[end] == '/') {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isPathChar(c byte) bool {
+	return c == '-' || c == '_' || c == '.' || c == '/' || c == '~' ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+func lineOf(content []byte, s string) int {
+	i := bytes.Index(content, []byte(s))
+	if i < 0 {
+		return 0
+	}
+	return bytes.Count(content[:i], []byte("\n")) + 1
+}
+
+// collectFields adds to `used` the top level fields, like `.tls`, referenced
+// by the template `text`.
+func collectFields(text string, used map[string]bool) error {
+	t, err := newTemplate("validate").Parse(text)
+	if err != nil {
+		return err
+	}
+	if t.Tree != nil {
+		walkFields(t.Tree.Root, used, false)
+	}
+	return nil
+}
+
+// walkFields collects the fields of `node` into `used`. Inside the body of a
+// range or with, `nested`, the dot is an element of the data, so only `$.x`
+// is a top level field there.
+func walkFields(node parse.Node, used map[string]bool, nested bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			walkFields(c, used, nested)
+		}
+	case *parse.ActionNode:
+		walkFields(n.Pipe, used, nested)
+	case *parse.IfNode:
+		walkFields(n.Pipe, used, nested)
+		walkFields(n.List, used, nested)
+		walkFields(n.ElseList, used, nested)
+	case *parse.RangeNode:
+		walkScope(&n.BranchNode, used, nested)
+	case *parse.WithNode:
+		walkScope(&n.BranchNode, used, nested)
+	case *parse.TemplateNode:
+		walkFields(n.Pipe, used, nested)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			walkFields(cmd, used, nested)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			walkFields(arg, used, nested)
+		}
+	case *parse.ChainNode:
+		walkFields(n.Node, used, nested)
+	case *parse.FieldNode:
+		if !nested {
+			used[n.Ident[0]] = true
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			used[n.Ident[1]] = true
+		}
+	}
+}
+
+// walkScope walks a range or with, whose body runs with a new dot and whose
+// else branch keeps the current one.
+func walkScope(n *parse.BranchNode, used map[string]bool, nested bool) {
+	walkFields(n.Pipe, used, nested)
+	walkFields(n.List, used, true)
+	walkFields(n.ElseList, used, nested)
+}
@@ -0,0 +1,49 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package layout
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCollectFields(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"{{.Name}} {{if .TLS}}{{.Port}}{{end}}", []string{"Name", "Port", "TLS"}},
+		{"{{range .Points}}{{.Name}}{{end}}", []string{"Points"}},
+		{"{{with .Broker}}{{.Host}}{{else}}{{.Default}}{{end}}", []string{"Broker", "Default"}},
+		{"{{range .Points}}{{$.Prefix}}{{.Name}}{{end}}", []string{"Points", "Prefix"}},
+		{"{{range $p := .Points}}{{$p.Name}}{{end}}", []string{"Points"}},
+		{"{{range .Points}}{{range .Fields}}{{.Type}}{{end}}{{end}}", []string{"Points"}},
+	}
+	for _, tt := range tests {
+		used := map[string]bool{}
+		if err := collectFields(tt.text, used); err != nil {
+			t.Fatalf("%s: %v", tt.text, err)
+		}
+		var got []string
+		for name := range used {
+			got = append(got, name)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
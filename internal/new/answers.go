@@ -0,0 +1,167 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package new
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/winc-link/hummingbird-cli/internal/layout"
+	"gopkg.in/yaml.v3"
+)
+
+// Answers are the replies to every question of hb new. They are read with
+// `--answers` and written with `--record-answers`, as yaml, or json when the
+// file name ends with `.json`.
+type Answers struct {
+	Name      string `yaml:"name,omitempty" json:"name,omitempty"`
+	Module    string `yaml:"module,omitempty" json:"module,omitempty"`
+	Dir       string `yaml:"dir,omitempty" json:"dir,omitempty"`
+	Protocol  string `yaml:"protocol,omitempty" json:"protocol,omitempty"`
+	Registry  string `yaml:"registry,omitempty" json:"registry,omitempty"`
+	RepoURL   string `yaml:"repo_url,omitempty" json:"repo_url,omitempty"`
+	From      string `yaml:"from,omitempty" json:"from,omitempty"`
+	Ref       string `yaml:"ref,omitempty" json:"ref,omitempty"`
+	Offline   bool   `yaml:"offline,omitempty" json:"offline,omitempty"`
+	Overwrite string `yaml:"overwrite,omitempty" json:"overwrite,omitempty"`
+	// Variables answers the variables of the template manifest.
+	Variables map[string]interface{} `yaml:"variables,omitempty" json:"variables,omitempty"`
+}
+
+// LoadAnswers reads an answers file.
+func LoadAnswers(path string) (*Answers, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	a := &Answers{}
+	if isJSON(path) {
+		err = json.Unmarshal(data, a)
+	} else {
+		err = yaml.Unmarshal(data, a)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse answers file %s failed: %w", path, err)
+	}
+	return a, nil
+}
+
+// Save writes the answers to `path`.
+func (a *Answers) Save(path string) error {
+	var (
+		data []byte
+		err  error
+	)
+	if isJSON(path) {
+		data, err = json.MarshalIndent(a, "", "  ")
+		data = append(data, '\n')
+	} else {
+		data, err = layout.MarshalYAML(a)
+	}
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err = os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func isJSON(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// applyFileAnswers applies the answers file `a` to the project built from
+// the flags. The flags given on the command line, `changed`, win over the
+// answers, which win over the values of the configuration and environment.
+func (p *Project) applyFileAnswers(changed func(flag string) bool, a *Answers) {
+	fields := map[string]*string{
+		"module":   &p.Module,
+		"dir":      &p.OutputDir,
+		"protocol": &p.Protocol,
+		"registry": &p.Registry,
+		"repo-url": &p.RepoURL,
+		"from":     &p.From,
+		"ref":      &p.Ref,
+	}
+	configured := map[string]string{}
+	for flag, field := range fields {
+		if !changed(flag) {
+			configured[flag], *field = *field, ""
+		}
+	}
+	p.applyAnswers(a)
+	for flag, v := range configured {
+		if *fields[flag] == "" {
+			*fields[flag] = v
+		}
+	}
+}
+
+// applyAnswers fills every question of the project not answered by a flag.
+func (p *Project) applyAnswers(a *Answers) {
+	fill := func(dst *string, v string) {
+		if *dst == "" {
+			*dst = v
+		}
+	}
+	fill(&p.ProjectName, a.Name)
+	fill(&p.Module, a.Module)
+	fill(&p.OutputDir, a.Dir)
+	fill(&p.Protocol, a.Protocol)
+	fill(&p.Registry, a.Registry)
+	fill(&p.RepoURL, a.RepoURL)
+	fill(&p.From, a.From)
+	fill(&p.Ref, a.Ref)
+	p.Offline = p.Offline || a.Offline
+	if p.Overwrite == OverwriteAsk && a.Overwrite != "" {
+		p.Overwrite = a.Overwrite
+	}
+	for name, v := range a.Variables {
+		if p.Variables == nil {
+			p.Variables = map[string]interface{}{}
+		}
+		if _, ok := p.Variables[name]; !ok {
+			p.Variables[name] = v
+		}
+	}
+}
+
+// answers returns the replies given so far. Overwrite is only recorded when
+// always or never was chosen up front, ask being the default.
+func (p *Project) answers() *Answers {
+	overwrite := p.Overwrite
+	if overwrite == OverwriteAsk {
+		overwrite = ""
+	}
+	return &Answers{
+		Name:      p.ProjectName,
+		Module:    p.Module,
+		Dir:       p.OutputDir,
+		Protocol:  p.Protocol,
+		Registry:  p.Registry,
+		RepoURL:   layout.StripCredentials(p.RepoURL),
+		From:      layout.StripCredentials(p.From),
+		Ref:       p.Ref,
+		Offline:   p.Offline,
+		Overwrite: overwrite,
+		Variables: p.Variables,
+	}
+}
@@ -0,0 +1,66 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package new
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/winc-link/hummingbird-cli/config"
+)
+
+func TestAnswersPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+	t.Setenv("HOME", dir)
+	t.Setenv("HB_PROTOCOL", "mqtt")
+	t.Setenv("HB_REGISTRY", "Gitee")
+	answers := filepath.Join(dir, "answers.yaml")
+	if err := os.WriteFile(answers, []byte("name: demo\nprotocol: tcp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	root := &cobra.Command{Use: "hb"}
+	root.AddCommand(CmdNew)
+	defer root.RemoveCommand(CmdNew)
+
+	tests := []struct {
+		args               []string
+		protocol, registry string
+	}{
+		// The answers win over $HB_PROTOCOL, $HB_REGISTRY fills the rest.
+		{[]string{"--answers", answers}, "tcp", "Gitee"},
+		{[]string{"--answers", answers, "--protocol", "udp", "--registry", "Github"}, "udp", "Github"},
+	}
+	for _, tt := range tests {
+		protocol, registry = "", ""
+		if err := CmdNew.ParseFlags(tt.args); err != nil {
+			t.Fatal(err)
+		}
+		if err := config.Init(CmdNew, true); err != nil {
+			t.Fatal(err)
+		}
+		a, err := LoadAnswers(answers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := NewProject()
+		p.applyFileAnswers(CmdNew.Flags().Changed, a)
+		if p.Protocol != tt.protocol || p.Registry != tt.registry {
+			t.Errorf("%v: protocol %q registry %q, want %q %q", tt.args, p.Protocol, p.Registry, tt.protocol, tt.registry)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package new
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/winc-link/hummingbird-cli/config"
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+	"github.com/winc-link/hummingbird-cli/internal/executor"
+	"github.com/winc-link/hummingbird-cli/utility"
+)
+
+// defaultGitignore is written by --git-init when neither the template nor
+// --gitignore provide a .gitignore.
+const defaultGitignore = `# Binaries and build outputs
+/bin/
+/build/
+/dist/
+*.exe
+*.test
+*.out
+
+# Editors and OS files
+.idea/
+.vscode/
+.DS_Store
+`
+
+// GitInit configures the repository created by hb new --git-init.
+type GitInit struct {
+	// Branch is the name of the initial branch.
+	Branch string
+	// Gitignore is a file copied to .gitignore, replacing the one of the template.
+	Gitignore string
+	// Remote is set as origin when not empty.
+	Remote string
+}
+
+// check fails early, before anything is generated, when the repository could
+// not be created or committed to.
+func (g *GitInit) check(ctx context.Context) error {
+	local := executor.Options{Timeout: executor.LocalTimeout}
+	if _, err := executor.Run(ctx, local, "git", "check-ref-format", "--branch", g.Branch); err != nil {
+		return errs.Wrap(errs.Validation, fmt.Errorf("invalid --git-branch %s: %w", g.Branch, err))
+	}
+	if g.Gitignore != "" {
+		if _, err := os.Stat(g.Gitignore); err != nil {
+			return errs.New(errs.Validation, "invalid --gitignore: %w", err)
+		}
+	}
+	if _, err := executor.Run(ctx, local, "git", "var", "GIT_COMMITTER_IDENT"); err != nil {
+		return errs.Wrap(errs.Validation, fmt.Errorf("git cannot commit, configure user.name and user.email: %w", err))
+	}
+	return nil
+}
+
+// initGit creates a fresh repository in the project folder and commits the
+// generated files, recording the template in the commit message.
+func (p *Project) initGit(ctx context.Context) error {
+	defer utility.Step("git init")()
+	g := p.Git
+	utility.Printf("git init (%s)", g.Branch)
+	if err := p.writeGitignore(); err != nil {
+		return err
+	}
+	steps := [][]string{
+		{"init", "-q"},
+		{"symbolic-ref", "HEAD", "refs/heads/" + g.Branch},
+		{"add", "-A"},
+		{"commit", "-q", "-m", p.commitMessage()},
+	}
+	if g.Remote != "" {
+		steps = append(steps, []string{"remote", "add", "origin", g.Remote})
+	}
+	for _, args := range steps {
+		opts := executor.Options{Dir: p.Dir, Timeout: executor.LocalTimeout}
+		if _, err := executor.Run(ctx, opts, "git", args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Project) writeGitignore() error {
+	path := filepath.Join(p.Dir, ".gitignore")
+	if p.Git.Gitignore != "" {
+		data, err := os.ReadFile(p.Git.Gitignore)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, data, 0644)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	return os.WriteFile(path, []byte(defaultGitignore), 0644)
+}
+
+// commitMessage returns the message of the initial commit.
+func (p *Project) commitMessage() string {
+	l := p.lock()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Initial commit\n\nGenerated by hb %s from template %s", config.Version, l.Template.Source)
+	if l.Template.Ref != "" {
+		fmt.Fprintf(&sb, "\nRef: %s", l.Template.Ref)
+	}
+	if l.Template.Commit != "" {
+		fmt.Fprintf(&sb, "\nCommit: %s", l.Template.Commit)
+	}
+	return sb.String() + "\n"
+}
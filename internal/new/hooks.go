@@ -0,0 +1,108 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package new
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/winc-link/hummingbird-cli/config"
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+	"github.com/winc-link/hummingbird-cli/internal/executor"
+	"github.com/winc-link/hummingbird-cli/utility"
+)
+
+// confirmHooks shows the hooks of the template and makes sure the user
+// agrees to run them, unless allowed by --allow-hooks.
+func (p *Project) confirmHooks() error {
+	if len(p.hooks.PreGenerate)+len(p.hooks.PostGenerate) == 0 || p.AllowHooks {
+		return nil
+	}
+	fmt.Fprintln(os.Stderr, "the template runs these commands in the project folder:")
+	p.printHooks()
+	if !interactive() {
+		return errs.New(errs.Validation, "hooks cannot be confirmed without a terminal or with --yes, allow them with --allow-hooks")
+	}
+	allow := false
+	err := ask(&survey.Confirm{
+		Message: "Run the template hooks?",
+		Help:    "The project is not created without them.",
+	}, &allow)
+	if err != nil {
+		return err
+	}
+	if !allow {
+		return errs.New(errs.Cancelled, "template hooks declined")
+	}
+	p.AllowHooks = true
+	return nil
+}
+
+func (p *Project) printHooks() {
+	for _, h := range p.hooks.PreGenerate {
+		fmt.Fprintf(os.Stderr, "  pre-generate:  %s\n", h)
+	}
+	for _, h := range p.hooks.PostGenerate {
+		fmt.Fprintf(os.Stderr, "  post-generate: %s\n", h)
+	}
+}
+
+// runHooks runs the hook commands of `stage` in the project folder, streaming
+// their output, and stops at the first failure. The hooks run in the staging
+// folder, $HB_WORK_DIR, and $HB_PROJECT_DIR is where the project ends up.
+func (p *Project) runHooks(ctx context.Context, stage string, commands []string) error {
+	defer utility.Step(stage + " hooks")()
+	work, err := filepath.Abs(p.Dir)
+	if err != nil {
+		return err
+	}
+	target := work
+	if p.target != "" {
+		if target, err = filepath.Abs(p.target); err != nil {
+			return err
+		}
+	}
+	for _, line := range commands {
+		utility.Printf("%s hook: %s", stage, line)
+		opts := executor.Options{
+			Dir: work,
+			Env: []string{
+				"HB_PROJECT_NAME=" + p.ProjectName,
+				"HB_MODULE=" + p.Module,
+				"HB_PROJECT_DIR=" + target,
+				"HB_WORK_DIR=" + work,
+				"HB_PLATFORM=" + config.Current().Get("platform"),
+			},
+			Stream: true,
+		}
+		name, args := shell(line)
+		if _, err := executor.Run(ctx, opts, name, args...); err != nil {
+			return fmt.Errorf("%s hook failed: %w", stage, err)
+		}
+	}
+	return nil
+}
+
+// shell returns the command running `line` with the shell of the platform.
+func shell(line string) (string, []string) {
+	if runtime.GOOS == "windows" {
+		return "cmd", []string{"/C", line}
+	}
+	return "sh", []string{"-c", line}
+}
@@ -0,0 +1,116 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package new
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/winc-link/hummingbird-cli/config"
+	"github.com/winc-link/hummingbird-cli/internal/layout"
+	"gopkg.in/yaml.v3"
+)
+
+// LockFile records, inside a generated project, where its template came from
+// and how it was generated.
+const LockFile = ".hb/project.lock"
+
+type Lock struct {
+	HbVersion string         `yaml:"hb_version"`
+	Created   time.Time      `yaml:"created"`
+	Template  LockedTemplate `yaml:"template"`
+	Answers   *Answers       `yaml:"answers"`
+}
+
+// LockedTemplate pins the template a project was generated from.
+type LockedTemplate struct {
+	// Source is a spec accepted by `hb new --from`.
+	Source string `yaml:"source"`
+	Ref    string `yaml:"ref,omitempty"`
+	// Commit is the resolved git commit, or the digest of an archive.
+	Commit string `yaml:"commit,omitempty"`
+}
+
+// ReadLock reads the lock file of the project in `dir`.
+func ReadLock(dir string) (*Lock, error) {
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(LockFile)))
+	if err != nil {
+		return nil, err
+	}
+	l := &Lock{}
+	if err = yaml.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("parse %s failed: %w", LockFile, err)
+	}
+	return l, nil
+}
+
+// Save writes the lock file into the project in `dir`.
+func (l *Lock) Save(dir string) error {
+	data, err := layout.MarshalYAML(l)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, filepath.FromSlash(LockFile))
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// writeLock records the template and answers of the project.
+func (p *Project) writeLock() error {
+	if err := p.lock().Save(p.Dir); err != nil {
+		return fmt.Errorf("write project lock failed: %w", err)
+	}
+	return nil
+}
+
+func (p *Project) lock() *Lock {
+	ref := ""
+	if g, ok := p.source.(*layout.GitSource); ok {
+		ref = g.Ref
+	}
+	return &Lock{
+		HbVersion: config.Version,
+		Created:   time.Now().UTC().Truncate(time.Second),
+		Template: LockedTemplate{
+			Source: p.source.Spec(),
+			Ref:    ref,
+			Commit: p.source.Revision(),
+		},
+		Answers: p.answers(),
+	}
+}
+
+// Regenerate generates into `dir`, from `src`, the project described by
+// `answers`, prompting only for template variables they do not answer.
+// `go mod tidy` and the template hooks are skipped. It returns the lock of the generated project.
+func Regenerate(ctx context.Context, dir string, answers *Answers, src layout.TemplateSource) (*Lock, error) {
+	p := &Project{
+		Overwrite: OverwriteNever,
+		source:    src,
+		skipTidy:  true,
+		skipHooks: true,
+	}
+	p.applyAnswers(answers)
+	p.Dir = dir
+	if err := p.generate(ctx, nil); err != nil {
+		return nil, err
+	}
+	return p.lock(), nil
+}
@@ -15,253 +15,552 @@
 package new
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/spf13/cobra"
 	"github.com/winc-link/hummingbird-cli/config"
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+	"github.com/winc-link/hummingbird-cli/internal/executor"
+	"github.com/winc-link/hummingbird-cli/internal/layout"
+	"github.com/winc-link/hummingbird-cli/utility"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/term"
+)
+
+const (
+	OverwriteAsk    = "ask"
+	OverwriteAlways = "always"
+	OverwriteNever  = "never"
 )
 
 type Project struct {
 	ProjectName string `survey:"name"`
+	// Dir is the folder the project is generated in, OutputDir/ProjectName.
+	Dir       string
+	OutputDir string
+	Module    string
+	Protocol  string
+	Registry  string
+	RepoURL   string
+	From      string
+	Ref       string
+	Offline   bool
+	Overwrite string
+	// Variables are the answers to the variables of the template manifest.
+	Variables map[string]interface{}
+	// AllowHooks runs the hooks of the template without asking.
+	AllowHooks bool
+	// Git, when not nil, initializes a repository in the generated project.
+	Git *GitInit
+
+	source layout.TemplateSource
+	// target is the final folder of the project while Dir is its staging folder.
+	target    string
+	hooks     layout.Hooks
+	skipTidy  bool
+	skipHooks bool
 }
 
 var CmdNew = &cobra.Command{
 	Use:     "new",
-	Example: "hb new demo-driver",
+	Example: "hb new demo-driver\nhb new demo-driver --protocol mqtt --registry Github --module example.com/demo-driver --overwrite never",
 	Short:   "create a new driver layout.",
-	Long:    `create a new driver layout.`,
-	Run:     run,
+	Long: `create a new driver layout.
+
+Every question can be answered with a flag. With --yes every question that
+has a default takes it and an existing folder is overwritten. When stdin is
+not a terminal, or with --yes, hb new fails with the list of unanswered
+questions instead of prompting.`,
+	RunE: run,
 }
 var (
 	repoURL     string
 	ProjectName string
+	offline     bool
+	from        string
+	protocol    string
+	registry    string
+	ref         string
+	modulePath  string
+	prefix      string
+	outputDir   string
+	overwrite   = OverwriteAsk
+	answersFile string
+	recordFile  string
+	vars        []string
+	noCache     bool
+	noFailover  bool
+	dryRun      bool
+	allowHooks  bool
+	gitInit     bool
+	gitInitConf = GitInit{Branch: "main"}
 )
 
 func init() {
-	//CmdNew.Flags().StringVarP(&repoURL, "repo-url", "r", repoURL, "layout repo")
+	CmdNew.Flags().StringVarP(&repoURL, "repo-url", "r", repoURL, "layout repo")
 	CmdNew.Flags().StringVarP(&ProjectName, "p", "p", ProjectName, "project name")
+	CmdNew.Flags().BoolVar(&offline, "offline", offline, "use the templates embedded in hb instead of cloning them")
+	CmdNew.Flags().StringVar(&from, "from", from, "template source: git URL, local directory, .tar.gz/.zip archive, HTTP(S) archive or embedded:<name>")
+	CmdNew.Flags().StringVar(&protocol, "protocol", protocol, "template name or protocol, see `hb template list`")
+	CmdNew.Flags().StringVar(&registry, "registry", registry, "registry to fetch the template from, e.g. Github or Gitee")
+	CmdNew.Flags().StringVar(&ref, "ref", ref, "git branch, tag or commit of the template")
+	CmdNew.Flags().StringVar(&modulePath, "module", modulePath, "go module path of the project (default <module-prefix>/<project name>)")
+	CmdNew.Flags().StringVar(&prefix, "module-prefix", prefix, "organization prefix of the default module path, env "+config.ModulePrefixEnv)
+	CmdNew.Flags().StringVar(&outputDir, "dir", outputDir, "folder the project is created in (default current folder)")
+	CmdNew.Flags().StringVar(&overwrite, "overwrite", overwrite, "what to do when the project folder exists: ask, always or never")
+	CmdNew.Flags().StringVar(&answersFile, "answers", answersFile, "yaml or json file answering the questions of hb new")
+	CmdNew.Flags().StringArrayVar(&vars, "var", vars, "name=value answer to a template variable, repeatable")
+	CmdNew.Flags().BoolVar(&noCache, "no-cache", noCache, "fetch the template from its source instead of the template cache")
+	CmdNew.Flags().BoolVar(&noFailover, "no-failover", noFailover, "only fetch the template from the chosen registry and its mirrors")
+	CmdNew.Flags().StringVar(&recordFile, "record-answers", recordFile, "write the answers of this session to a yaml or json file")
+	CmdNew.Flags().BoolVar(&allowHooks, "allow-hooks", allowHooks, "run the pre and post generate hooks of the template without asking")
+	CmdNew.Flags().BoolVar(&gitInit, "git-init", gitInit, "initialize a git repository with an initial commit in the project")
+	CmdNew.Flags().StringVar(&gitInitConf.Branch, "git-branch", gitInitConf.Branch, "initial branch of --git-init")
+	CmdNew.Flags().StringVar(&gitInitConf.Gitignore, "gitignore", gitInitConf.Gitignore, "file used as .gitignore by --git-init (default the one of the template, or a built-in one)")
+	CmdNew.Flags().StringVar(&gitInitConf.Remote, "git-remote", gitInitConf.Remote, "origin remote URL set by --git-init")
+	CmdNew.Flags().BoolVar(&dryRun, "dry-run", dryRun, "print the files, module rewrites and commands of the project without creating it")
 
 }
 func NewProject() *Project {
-	return &Project{}
+	var git *GitInit
+	if gitInit {
+		conf := gitInitConf
+		git = &conf
+	}
+	return &Project{
+		ProjectName: ProjectName,
+		Module:      modulePath,
+		OutputDir:   outputDir,
+		Protocol:    protocol,
+		Registry:    registry,
+		RepoURL:     repoURL,
+		From:        from,
+		Ref:         ref,
+		Offline:     offline,
+		Overwrite:   overwrite,
+		AllowHooks:  allowHooks,
+		Git:         git,
+	}
 }
 
-func run(cmd *cobra.Command, args []string) {
+func run(cmd *cobra.Command, args []string) error {
 	p := NewProject()
-	if len(args) == 0 {
-		err := survey.AskOne(&survey.Input{
+	if len(args) > 0 {
+		p.ProjectName = args[0]
+	}
+	for _, v := range vars {
+		name, value, ok := strings.Cut(v, "=")
+		if !ok || name == "" {
+			return errs.New(errs.Validation, "invalid --var %q, expect name=value", v)
+		}
+		if p.Variables == nil {
+			p.Variables = map[string]interface{}{}
+		}
+		p.Variables[name] = value
+	}
+	if answersFile != "" {
+		a, err := LoadAnswers(answersFile)
+		if err != nil {
+			return errs.Wrap(errs.Validation, fmt.Errorf("load answers failed: %w", err))
+		}
+		p.applyFileAnswers(cmd.Flags().Changed, a)
+	}
+	if p.Overwrite != OverwriteAsk && p.Overwrite != OverwriteAlways && p.Overwrite != OverwriteNever {
+		return errs.New(errs.Validation, "invalid --overwrite %s, expect ask, always or never", p.Overwrite)
+	}
+	if p.Git != nil {
+		if err := p.Git.check(cmd.Context()); err != nil {
+			return err
+		}
+	}
+
+	reg, err := layout.LoadRegistry()
+	if err != nil {
+		return fmt.Errorf("load template registry failed: %w", err)
+	}
+	if !interactive() {
+		if missing := p.missingAnswers(reg); len(missing) > 0 {
+			return missingError(missing)
+		}
+	}
+
+	if p.ProjectName == "" {
+		err := ask(&survey.Input{
 			Message: "What is your project name?",
 			Help:    "project name.",
 			Suggest: nil,
 		}, &p.ProjectName, survey.WithValidator(survey.Required))
 		if err != nil {
-			return
+			return err
 		}
-	} else {
-		p.ProjectName = args[0]
 	}
-
-	// clone repo
-	yes, err := p.cloneTemplate()
-	if err != nil || !yes {
-		return
+	p.Dir = filepath.Join(p.OutputDir, p.ProjectName)
+	if err = p.askModule(); err != nil {
+		return err
 	}
-
-	err = p.replacePackageName()
-	if err != nil || !yes {
-		return
+	if dryRun {
+		return p.preview(cmd.Context(), reg)
 	}
 
-	err = p.modTidy()
-	if err != nil || !yes {
-		return
+	if err = p.confirmOverwrite(); err != nil {
+		return err
 	}
-	p.rmGit()
-	fmt.Printf(config.LogoContent + "\n")
-	fmt.Printf("🎉 Project \u001B[36m%s\u001B[0m created successfully!\n\n", p.ProjectName)
-}
 
-func (p *Project) cloneTemplate() (bool, error) {
-	stat, _ := os.Stat(p.ProjectName)
-	if stat != nil {
-		var overwrite = false
-
-		prompt := &survey.Confirm{
-			Message: fmt.Sprintf("Folder %s already exists, do you want to overwrite it?", p.ProjectName),
-			Help:    "Remove old project and create new project.",
-		}
-		err := survey.AskOne(prompt, &overwrite)
-		if err != nil {
-			return false, err
-		}
-		if !overwrite {
-			return false, nil
-		}
-		err = os.RemoveAll(p.ProjectName)
-		if err != nil {
-			fmt.Println("remove old project error: ", err)
-			return false, err
-		}
+	// Generate in a staging folder and move it into place once every step succeeded.
+	st, err := newStage(p.Dir)
+	if err != nil {
+		return fmt.Errorf("create staging folder failed: %w", err)
 	}
-	repo := ""
-
-	if repoURL == "" {
-		protocol := ""
-		prompt := &survey.Select{
-			Message: "Please select a protocol:",
-			Options: []string{
-				"MQTT",
-				"TCP",
-				"UDP",
-				"CoAP",
-				"HTTP",
-				"WebSocket",
-				"Modbus-TCP",
-				"OPC-UA",
-			},
-			//Description: func(value string, index int) string {
-			//	if index == 1 {
-			//		return "A basic project structure"
-			//	}
-			//	return "It has rich functions such as db, jwt, cron, migration, test, etc"
-			//},
-		}
-		err := survey.AskOne(prompt, &protocol)
-		if err != nil {
-			return false, err
-		}
-		registry := ""
-		prompt2 := &survey.Select{
-			Message: "Please select a registry:",
-			Options: []string{
-				"Github",
-				"Gitee",
-			},
+	target := p.Dir
+	p.target = target
+	p.Dir = st.dir
+	err = p.generate(cmd.Context(), reg)
+	if err == nil && cmd.Context().Err() != nil {
+		// Interrupted after the last command, the project is not moved in place.
+		err = errs.New(errs.Cancelled, "generation interrupted")
+	}
+	if err != nil {
+		st.rollback()
+		if errs.KindOf(err) == errs.Cancelled {
+			utility.Warn("interrupted, nothing was changed")
 		}
-		err = survey.AskOne(prompt2, &registry)
-		if err != nil {
-			return false, err
+		return err
+	}
+	if err = st.commit(); err != nil {
+		return fmt.Errorf("move project into place failed: %w", err)
+	}
+	p.Dir = target
+	if recordFile != "" {
+		if err = p.answers().Save(recordFile); err != nil {
+			return fmt.Errorf("record answers failed: %w", err)
 		}
+		utility.Printf("answers recorded to %s", recordFile)
+	}
+	if !utility.Quiet() && utility.Format() == utility.FormatText {
+		fmt.Fprintln(os.Stderr, utility.Colored(config.LogoContent))
+	}
+	utility.Resultf("🎉 Project \u001B[36m%s\u001B[0m created successfully!", p.ProjectName)
+	return nil
+}
 
-		switch registry {
-		case "Github":
-			repo = config.GitHubAddr
-		case "Gitee":
-			repo = config.GiteeAddr
-		}
+// isTerminal reports whether questions can be asked on stdin.
+func isTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// ask asks `prompt` on stderr, stdout is kept for the results of hb.
+func ask(prompt survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
+	return survey.AskOne(prompt, response, append(opts, survey.WithStdio(os.Stdin, os.Stderr, os.Stderr))...)
+}
 
-		switch protocol {
-		case "MQTT":
-			repo = repo + config.MqttProtocolDriver
-		case "TCP":
-			repo = repo + config.TcpProtocolDriver
-		case "UDP":
-			repo = repo + config.UdpProtocolDriver
-		case "CoAP":
-			repo = repo + config.CoapProtocolDriver
-		case "HTTP":
-			repo = repo + config.HttpProtocolDriver
-		case "WebSocket":
-			repo = repo + config.WebSocketProtocolDriver
-		case "Modbus-TCP":
-			repo = repo + config.ModbusProtocolDriver
-		case "OPC-UA":
-			repo = repo + config.OpcuaProtocolDriver
+// interactive reports whether questions are asked, that is when stdin is a
+// terminal and --yes is not set.
+func interactive() bool {
+	return !utility.Check() && isTerminal()
+}
+
+// missingError reports the answers that cannot be asked.
+func missingError(missing []string) error {
+	reason := "stdin is not a terminal"
+	if utility.Check() {
+		reason = "--yes is set"
+	}
+	return errs.New(errs.Validation, "%s and these answers are missing:\n  %s", reason, strings.Join(missing, "\n  "))
+}
+
+// missingAnswers lists the questions that would have to be prompted for.
+func (p *Project) missingAnswers(reg *layout.Registry) []string {
+	var missing []string
+	if p.ProjectName == "" {
+		missing = append(missing, "project name (argument or -p)")
+	}
+	if p.From == "" && p.RepoURL == "" {
+		tpl := findTemplate(reg, p.Protocol)
+		if tpl == nil {
+			missing = append(missing, "protocol (--protocol)")
 		}
-		err = os.RemoveAll(p.ProjectName)
-		if err != nil {
-			fmt.Println("remove old project error: ", err)
-			return false, err
+		if !p.Offline && p.Registry == "" && !utility.Check() && (tpl == nil || len(tpl.Sources) > 1) {
+			missing = append(missing, "registry (--registry)")
 		}
-	} else {
-		repo = repoURL
 	}
-	fmt.Printf("git clone %s\n", repo)
-	cmd := exec.Command("git", "clone", repo, p.ProjectName)
-	_, err := cmd.CombinedOutput()
-	if err != nil {
-		fmt.Printf("git clone %s error: %s\n", repo, err)
-		return false, err
+	if p.ProjectName != "" && p.Overwrite == OverwriteAsk && !dryRun && !utility.Check() {
+		if stat, _ := os.Stat(filepath.Join(p.OutputDir, p.ProjectName)); stat != nil {
+			missing = append(missing, "overwrite existing folder (--overwrite always|never)")
+		}
 	}
-	return true, nil
+	return missing
 }
 
-func (p *Project) replacePackageName() error {
-	packageName := getProjectName(p.ProjectName)
+// confirmOverwrite decides whether an existing project folder may be replaced,
+// failing otherwise. The folder itself is only replaced once the new project
+// is complete.
+func (p *Project) confirmOverwrite() error {
+	stat, _ := os.Stat(p.Dir)
+	if stat == nil || p.Overwrite == OverwriteAlways {
+		return nil
+	}
+	if p.Overwrite == OverwriteAsk && utility.Check() {
+		return nil
+	}
+	if p.Overwrite == OverwriteNever {
+		return errs.New(errs.Validation, "folder %s already exists", p.Dir)
+	}
 
-	err := p.replaceFiles(packageName)
-	if err != nil {
+	overwrite := false
+	prompt := &survey.Confirm{
+		Message: fmt.Sprintf("Folder %s already exists, do you want to overwrite it?", p.Dir),
+		Help:    "Remove old project and create new project.",
+	}
+	if err := ask(prompt, &overwrite); err != nil {
 		return err
 	}
+	if !overwrite {
+		return errs.New(errs.Cancelled, "folder %s already exists", p.Dir)
+	}
+	// p.Overwrite stays ask: the reply is for this folder only, and must not
+	// be recorded as always for the replays of the answers.
+	return nil
+}
 
-	cmd := exec.Command("go", "mod", "edit", "-module", p.ProjectName)
-	cmd.Dir = p.ProjectName
-	_, err = cmd.CombinedOutput()
-	if err != nil {
-		fmt.Println("go mod edit error: ", err)
+// generate runs every step creating the project in p.Dir.
+func (p *Project) generate(ctx context.Context, reg *layout.Registry) error {
+	defer utility.Step("generate " + p.ProjectName)()
+	if err := p.cloneTemplate(ctx, reg); err != nil {
+		return err
+	}
+	if err := p.renderTemplate(ctx); err != nil {
+		return err
+	}
+	if err := p.replacePackageName(); err != nil {
+		return err
+	}
+	if !p.skipTidy {
+		if err := p.modTidy(ctx); err != nil {
+			return err
+		}
+	}
+	p.rmGit()
+	if err := p.writeLock(); err != nil {
 		return err
 	}
+	if !p.skipHooks {
+		if err := p.runHooks(ctx, "post-generate", p.hooks.PostGenerate); err != nil {
+			return err
+		}
+	}
+	if p.Git != nil {
+		return p.initGit(ctx)
+	}
 	return nil
 }
-func (p *Project) modTidy() error {
-	fmt.Println("go mod tidy")
-	cmd := exec.Command("go", "mod", "tidy")
-	cmd.Dir = p.ProjectName
-	if err := cmd.Run(); err != nil {
-		fmt.Println("go mod tidy error: ", err)
+
+func (p *Project) cloneTemplate(ctx context.Context, reg *layout.Registry) error {
+	defer utility.Step("fetch template")()
+	candidates := []layout.Candidate{{Registry: p.Registry, Source: p.source}}
+	if p.source == nil {
+		var err error
+		if candidates, err = p.templateSources(reg); err != nil {
+			return fmt.Errorf("resolve template failed: %w", err)
+		}
+	}
+	utility.Print(candidates[0].Source.String())
+	c, err := layout.Failover(ctx, candidates, p.Dir, fetch)
+	if err != nil {
 		return err
 	}
+	p.source = c.Source
+	if c.Registry != p.Registry {
+		utility.Printf("template fetched from %s instead of %s", c.Registry, p.Registry)
+		p.Registry = c.Registry
+	}
 	return nil
 }
-func (p *Project) rmGit() {
-	os.RemoveAll(p.ProjectName + "/.git")
+
+// SetNoCache makes the templates be fetched from their source instead of the
+// template cache, for commands regenerating projects such as upgrade-project.
+func SetNoCache(disable bool) {
+	noCache = disable
+}
+
+// fetch fetches the template through the template cache, unless disabled.
+func fetch(ctx context.Context, src layout.TemplateSource, dst string) error {
+	if noCache {
+		return src.Fetch(ctx, dst)
+	}
+	cache, err := layout.OpenCache()
+	if err != nil {
+		utility.Warnf("open template cache failed: %s, fetch without it", err)
+		return src.Fetch(ctx, dst)
+	}
+	return cache.Fetch(ctx, src, dst)
 }
 
-func (p *Project) replaceFiles(packageName string) error {
-	err := filepath.Walk(p.ProjectName, func(path string, info os.FileInfo, err error) error {
+// templateSources resolves where the template comes from: the `--from` spec,
+// the repo URL, or the registry entry chosen by flags or the interactive menu
+// followed, unless --no-failover, by the other registries of the template.
+func (p *Project) templateSources(reg *layout.Registry) ([]layout.Candidate, error) {
+	candidates, err := p.resolveSources(reg)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range candidates {
+		if g, ok := c.Source.(*layout.GitSource); ok && p.Ref != "" {
+			g.Ref = p.Ref
+		}
+	}
+	return candidates, nil
+}
+
+func (p *Project) resolveSources(reg *layout.Registry) ([]layout.Candidate, error) {
+	if p.From != "" {
+		src, err := layout.ParseSource(p.From)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if info.IsDir() {
-			return nil
+		return []layout.Candidate{{Source: src}}, nil
+	}
+	if p.RepoURL != "" {
+		return []layout.Candidate{{Source: &layout.GitSource{URL: p.RepoURL}}}, nil
+	}
+
+	tpl := findTemplate(reg, p.Protocol)
+	if tpl == nil {
+		if p.Protocol != "" {
+			return nil, errs.New(errs.Validation, "unknown protocol %s, see `hb template list`", p.Protocol)
 		}
-		if filepath.Ext(path) != ".go" {
-			return nil
+		var err error
+		if tpl, err = selectTemplate(reg); err != nil {
+			return nil, err
 		}
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return err
-		}
-		newData := bytes.ReplaceAll(data, []byte(packageName), []byte(p.ProjectName))
-		if err := os.WriteFile(path, newData, 0644); err != nil {
-			return err
+		p.Protocol = tpl.Name
+	}
+	if p.Offline {
+		p.Registry = layout.EmbeddedRegistry
+	}
+	if p.Registry == "" {
+		var err error
+		if p.Registry, err = selectRegistry(tpl); err != nil {
+			return nil, err
 		}
+	}
+	return tpl.Candidates(p.Registry, p.Offline || noFailover)
+}
+
+// findTemplate returns the template matching `name` by name, then by protocol tag.
+func findTemplate(reg *layout.Registry, name string) *layout.Template {
+	if name == "" {
 		return nil
-	})
-	if err != nil {
-		fmt.Println("walk file error: ", err)
-		return err
+	}
+	if t := reg.Get(name); t != nil {
+		return t
+	}
+	for _, t := range reg.Templates {
+		for _, tag := range t.Protocols {
+			if strings.EqualFold(tag, name) {
+				return t
+			}
+		}
 	}
 	return nil
 }
 
-func getProjectName(dir string) string {
-	modFile, err := os.Open(dir + "/go.mod")
+// selectTemplate asks for one of the templates of the registry.
+func selectTemplate(reg *layout.Registry) (*layout.Template, error) {
+	if len(reg.Templates) == 0 {
+		return nil, errs.New(errs.Validation, "template registry is empty, add one with `hb template add`")
+	}
+	options := make([]string, 0, len(reg.Templates))
+	for _, t := range reg.Templates {
+		options = append(options, t.Name)
+	}
+	name := ""
+	prompt := &survey.Select{
+		Message: "Please select a protocol:",
+		Options: options,
+		Description: func(value string, index int) string {
+			return reg.Templates[index].Description
+		},
+	}
+	if err := ask(prompt, &name); err != nil {
+		return nil, err
+	}
+	return reg.Get(name), nil
+}
+
+// selectRegistry asks which registry to fetch the template from.
+func selectRegistry(tpl *layout.Template) (string, error) {
+	registries := tpl.Registries()
+	if len(registries) == 0 {
+		return "", errs.New(errs.Validation, "template %s has no sources", tpl.Name)
+	}
+	if len(registries) == 1 || utility.Check() {
+		return registries[0], nil
+	}
+	registry := ""
+	prompt := &survey.Select{
+		Message: "Please select a registry:",
+		Options: registries,
+	}
+	err := ask(prompt, &registry)
+	return registry, err
+}
+
+func (p *Project) replacePackageName() error {
+	defer utility.Step("rewrite module")()
+	packageName, err := layout.ModulePath(p.Dir)
 	if err != nil {
-		fmt.Println("go.mod does not exist", err)
-		return ""
+		return errs.Wrap(errs.Validation, fmt.Errorf("read go mod failed: %w", err))
 	}
-	defer modFile.Close()
 
-	var moduleName string
-	_, err = fmt.Fscanf(modFile, "module %s", &moduleName)
+	changed, err := layout.RewriteModule(p.Dir, packageName, p.Module)
 	if err != nil {
-		fmt.Println("read go mod error: ", err)
-		return ""
+		return fmt.Errorf("rewrite module failed: %w", err)
+	}
+	for _, file := range changed {
+		utility.Printf("rewrite %s", file)
 	}
-	return moduleName
+	return nil
+}
+
+// askModule settles the go module path of the project, which defaults to
+// the module prefix joined with the project folder name.
+func (p *Project) askModule() error {
+	if p.Module == "" {
+		p.Module = filepath.Base(p.ProjectName)
+		if prefix != "" {
+			p.Module = strings.TrimSuffix(prefix, "/") + "/" + p.Module
+		}
+		if interactive() {
+			err := ask(&survey.Input{
+				Message: "What is your go module path?",
+				Help:    "module path written to go.mod and used by the imports, e.g. git.company.com/iot/drivers/demo.",
+				Default: p.Module,
+			}, &p.Module, survey.WithValidator(func(ans interface{}) error {
+				return module.CheckImportPath(ans.(string))
+			}))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if err := module.CheckImportPath(p.Module); err != nil {
+		return errs.New(errs.Validation, "invalid module path: %w", err)
+	}
+	return nil
+}
+
+func (p *Project) modTidy(ctx context.Context) error {
+	utility.Print("go mod tidy")
+	// go mod tidy downloads the dependencies.
+	opts := executor.Options{Dir: p.Dir, Timeout: executor.NetworkTimeout, TimeoutKind: errs.Network, Stream: true}
+	_, err := executor.Run(ctx, opts, "go", "mod", "tidy")
+	return err
+}
+func (p *Project) rmGit() {
+	os.RemoveAll(filepath.Join(p.Dir, ".git"))
 }
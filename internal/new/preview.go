@@ -0,0 +1,124 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package new
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+	"github.com/winc-link/hummingbird-cli/internal/layout"
+)
+
+// preview generates the project into a temporary folder and prints what hb
+// new would create, without touching p.Dir.
+func (p *Project) preview(ctx context.Context, reg *layout.Registry) error {
+	target := p.Dir
+	tmp, err := os.MkdirTemp("", "hb-dry-run-*")
+	if err != nil {
+		return fmt.Errorf("create temp folder failed: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	p.Dir = filepath.Join(tmp, "project")
+	p.skipHooks = true
+	defer func() { p.Dir = target }()
+
+	if err = p.cloneTemplate(ctx, reg); err != nil {
+		return err
+	}
+	if err = p.renderTemplate(ctx); err != nil {
+		return err
+	}
+	p.rmGit()
+	rendered := filepath.Join(tmp, "rendered")
+	if err = layout.CopyDir(p.Dir, rendered, nil); err != nil {
+		return fmt.Errorf("copy template failed: %w", err)
+	}
+	from, err := layout.ModulePath(p.Dir)
+	if err != nil {
+		return errs.Wrap(errs.Validation, fmt.Errorf("read go mod failed: %w", err))
+	}
+	changed, err := layout.RewriteModule(p.Dir, from, p.Module)
+	if err != nil {
+		return fmt.Errorf("rewrite module failed: %w", err)
+	}
+	if err = p.writeLock(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\ndry run, nothing is written to %s\n", target)
+	if stat, _ := os.Stat(target); stat != nil {
+		fmt.Printf("the existing folder would be replaced (--overwrite %s)\n", p.Overwrite)
+	}
+
+	fmt.Println("\nfiles:")
+	if err = printTree(p.Dir, filepath.Base(target)); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nmodule %s rewritten to %s in %d files:\n", from, p.Module, len(changed))
+	for _, rel := range changed {
+		before, err := os.ReadFile(filepath.Join(rendered, filepath.FromSlash(rel)))
+		if err != nil {
+			return err
+		}
+		after, err := os.ReadFile(filepath.Join(p.Dir, filepath.FromSlash(rel)))
+		if err != nil {
+			return err
+		}
+		fmt.Print(layout.UnifiedDiff(rel, before, after))
+	}
+
+	fmt.Println("\ncommands:")
+	for _, h := range p.hooks.PreGenerate {
+		fmt.Printf("  %s  (pre-generate hook, before rendering)\n", h)
+	}
+	fmt.Printf("  cd %s && go mod tidy\n", target)
+	for _, h := range p.hooks.PostGenerate {
+		fmt.Printf("  %s  (post-generate hook)\n", h)
+	}
+	if p.Git != nil {
+		fmt.Printf("  git init && git symbolic-ref HEAD refs/heads/%s\n", p.Git.Branch)
+		fmt.Println("  git add -A && git commit")
+		if p.Git.Remote != "" {
+			fmt.Printf("  git remote add origin %s\n", p.Git.Remote)
+		}
+	}
+	return nil
+}
+
+// printTree prints the files under `dir`, indented by depth below `root`.
+func printTree(dir, root string) error {
+	fmt.Printf("  %s/\n", root)
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		depth := strings.Count(filepath.ToSlash(rel), "/") + 1
+		name := info.Name()
+		if info.IsDir() {
+			name += "/"
+		}
+		fmt.Printf("  %s%s\n", strings.Repeat("  ", depth), name)
+		return nil
+	})
+}
@@ -0,0 +1,103 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package new
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/winc-link/hummingbird-cli/utility"
+)
+
+// stage is a staging folder next to the project folder `dst`. The project is
+// generated in `dir` and only replaces `dst` on commit; the previous `dst` is
+// kept as a backup inside the staging folder until then.
+type stage struct {
+	mu     sync.Mutex
+	dst    string
+	root   string
+	dir    string
+	backup string
+	done   bool
+}
+
+func newStage(dst string) (*stage, error) {
+	parent := filepath.Dir(dst)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return nil, err
+	}
+	// The staging folder shares the parent of dst so the final rename stays on one file system.
+	root, err := os.MkdirTemp(parent, "."+filepath.Base(dst)+".hb-")
+	if err != nil {
+		return nil, err
+	}
+	return &stage{
+		dst:  dst,
+		root: root,
+		dir:  filepath.Join(root, filepath.Base(dst)),
+	}, nil
+}
+
+// commit moves the generated project to its destination, backing up and
+// finally dropping the previous folder. Nothing is changed if it fails.
+func (s *stage) commit() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return fmt.Errorf("staging folder %s already released", s.root)
+	}
+	if _, err := os.Lstat(s.dst); err == nil {
+		s.backup = filepath.Join(s.root, "backup")
+		if err = os.Rename(s.dst, s.backup); err != nil {
+			s.backup = ""
+			s.release()
+			return err
+		}
+	}
+	if err := os.Rename(s.dir, s.dst); err != nil {
+		s.release()
+		return err
+	}
+	s.backup = ""
+	s.release()
+	return nil
+}
+
+// rollback drops the staging folder and restores the previous project folder.
+func (s *stage) rollback() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.done {
+		s.release()
+	}
+}
+
+// release restores the backup, if still pending, and removes the staging folder.
+func (s *stage) release() {
+	if s.backup != "" {
+		if err := os.Rename(s.backup, s.dst); err != nil {
+			utility.Warnf("restore %s failed: %s, the previous project is kept in %s", s.dst, err, s.backup)
+			s.done = true
+			return
+		}
+		s.backup = ""
+	}
+	if err := os.RemoveAll(s.root); err != nil {
+		utility.Warnf("remove staging folder failed: %s", err)
+	}
+	s.done = true
+}
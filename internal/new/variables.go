@@ -0,0 +1,178 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package new
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+	"github.com/winc-link/hummingbird-cli/internal/layout"
+	"github.com/winc-link/hummingbird-cli/utility"
+)
+
+// renderTemplate asks the variables declared by the template manifest, if
+// any, and renders the template with them.
+func (p *Project) renderTemplate(ctx context.Context) error {
+	m, err := layout.LoadManifest(p.Dir)
+	if err != nil {
+		return errs.Wrap(errs.Validation, fmt.Errorf("load template manifest failed: %w", err))
+	}
+	if m == nil {
+		return nil
+	}
+	if err = p.askVariables(m); err != nil {
+		return err
+	}
+	if p.hooks, err = m.RenderHooks(p.templateData()); err != nil {
+		return errs.Wrap(errs.Validation, fmt.Errorf("render template hooks failed: %w", err))
+	}
+	if !p.skipHooks {
+		if err = p.confirmHooks(); err != nil {
+			return err
+		}
+		if err = p.runHooks(ctx, "pre-generate", p.hooks.PreGenerate); err != nil {
+			return err
+		}
+	}
+	utility.Print("render template")
+	defer utility.Step("render template")()
+	if err = m.Apply(p.Dir, p.templateData()); err != nil {
+		return errs.Wrap(errs.Validation, fmt.Errorf("render template failed: %w", err))
+	}
+	return nil
+}
+
+// templateData returns the data the template is rendered with.
+func (p *Project) templateData() map[string]interface{} {
+	data := map[string]interface{}{
+		"ProjectName": p.ProjectName,
+		"Module":      p.Module,
+	}
+	for k, v := range p.Variables {
+		data[k] = v
+	}
+	return data
+}
+
+// askVariables fills p.Variables for every variable of the manifest, from the
+// flags and answers file first, then by prompting. Without a terminal the
+// defaults are used and required variables must be answered.
+func (p *Project) askVariables(m *layout.Manifest) error {
+	if p.Variables == nil {
+		p.Variables = map[string]interface{}{}
+	}
+	for name := range p.Variables {
+		if m.Variable(name) == nil {
+			utility.Warnf("variable %s is not declared by the template, ignored", name)
+			delete(p.Variables, name)
+		}
+	}
+
+	var missing []string
+	for _, v := range m.Variables {
+		if val, ok := p.Variables[v.Name]; ok {
+			norm, err := v.Normalize(val)
+			if err != nil {
+				return errs.New(errs.Validation, "variable %s: %w", v.Name, err)
+			}
+			p.Variables[v.Name] = norm
+			continue
+		}
+		if !interactive() {
+			if val, ok := defaultValue(v); ok {
+				p.Variables[v.Name] = val
+			} else {
+				missing = append(missing, fmt.Sprintf("%s (--var %s=...)", v.Name, v.Name))
+			}
+			continue
+		}
+		val, err := askVariable(v)
+		if err != nil {
+			return err
+		}
+		p.Variables[v.Name] = val
+	}
+	if len(missing) > 0 {
+		return missingError(missing)
+	}
+	return nil
+}
+
+// defaultValue returns the value of `v` when it is not asked.
+func defaultValue(v *layout.Variable) (interface{}, bool) {
+	if v.Default != nil {
+		return v.Default, true
+	}
+	if v.Required || v.Type == layout.TypeChoice {
+		return nil, false
+	}
+	switch v.Type {
+	case layout.TypeBool:
+		return false, true
+	case layout.TypeMulti:
+		return []string{}, true
+	}
+	return "", true
+}
+
+func askVariable(v *layout.Variable) (interface{}, error) {
+	message := v.Prompt
+	if message == "" {
+		message = v.Name + ":"
+	}
+	validator := survey.WithValidator(func(ans interface{}) error {
+		if options, ok := ans.([]survey.OptionAnswer); ok {
+			list := make([]string, 0, len(options))
+			for _, o := range options {
+				list = append(list, o.Value)
+			}
+			ans = list
+		}
+		_, err := v.Normalize(ans)
+		return err
+	})
+
+	switch v.Type {
+	case layout.TypeBool:
+		answer, _ := v.Default.(bool)
+		err := ask(&survey.Confirm{Message: message, Help: v.Help, Default: answer}, &answer)
+		return answer, err
+	case layout.TypeChoice:
+		answer := ""
+		prompt := &survey.Select{Message: message, Help: v.Help, Options: v.Choices}
+		if v.Default != nil {
+			prompt.Default = v.Default
+		}
+		err := ask(prompt, &answer)
+		return answer, err
+	case layout.TypeMulti:
+		var answer []string
+		prompt := &survey.MultiSelect{Message: message, Help: v.Help, Options: v.Choices}
+		if v.Default != nil {
+			prompt.Default = v.Default
+		}
+		err := ask(prompt, &answer, validator)
+		return answer, err
+	}
+	answer := ""
+	prompt := &survey.Input{Message: message, Help: v.Help}
+	if v.Default != nil {
+		prompt.Default = v.Default.(string)
+	}
+	err := ask(prompt, &answer, validator)
+	return answer, err
+}
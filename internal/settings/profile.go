@@ -0,0 +1,171 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package settings
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/winc-link/hummingbird-cli/config"
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+)
+
+var CmdProfile = &cobra.Command{
+	Use:   "profile",
+	Short: "manage the named configuration profiles.",
+	Long: `manage the named configuration profiles.
+
+A profile groups the registry, template registry file, proxy, module prefix
+and platform endpoint of an environment, e.g. home or office. The active
+profile is chosen by --profile, $HB_PROFILE or the profile key of the
+configuration, and overrides the keys of the configuration files.`,
+}
+
+var cmdProfileList = &cobra.Command{
+	Use:     "list",
+	Example: "hb profile list",
+	Short:   "list the profiles, the active one marked with *.",
+	Args:    cobra.NoArgs,
+	RunE:    runProfileList,
+}
+
+var cmdProfileUse = &cobra.Command{
+	Use:     "use <name>",
+	Example: "hb profile use office",
+	Short:   "make a profile the active one.",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runProfileUse,
+}
+
+var cmdProfileCreate = &cobra.Command{
+	Use:     "create <name>",
+	Example: "hb profile create office --registry Gitee --proxy http://proxy.company.com:3128 --module-prefix git.company.com/iot/drivers",
+	Short:   "create or replace a profile.",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runProfileCreate,
+}
+
+var (
+	profileValues = map[string]*string{}
+	force         bool
+	activate      bool
+)
+
+func init() {
+	descriptions := map[string]string{
+		"registry":      "registry templates are fetched from, e.g. Gitee",
+		"templates":     "template registry file used instead of ~/.hb/templates.yaml",
+		"proxy":         "HTTP(S) proxy of git and of the downloads",
+		"module-prefix": "organization prefix of the default module path",
+		"platform":      "Hummingbird platform endpoint",
+		"ssh-key":       "private key of the SSH template repositories",
+	}
+	for _, key := range config.ProfileKeys {
+		profileValues[key] = cmdProfileCreate.Flags().String(key, "", descriptions[key])
+	}
+	cmdProfileCreate.Flags().BoolVar(&force, "force", force, "replace the profile if it exists in the file")
+	cmdProfileCreate.Flags().BoolVar(&activate, "use", activate, "make the profile the active one")
+	for _, c := range []*cobra.Command{cmdProfileUse, cmdProfileCreate} {
+		c.Flags().BoolVar(&system, "system", system, "edit "+config.SystemFile()+" instead of the user file")
+		c.Flags().BoolVar(&project, "project", project, "edit the project file "+config.ProjectFile+" instead of the user file")
+		c.MarkFlagsMutuallyExclusive("system", "project")
+	}
+
+	CmdProfile.AddCommand(cmdProfileList, cmdProfileUse, cmdProfileCreate)
+}
+
+func runProfileList(cmd *cobra.Command, args []string) error {
+	profiles, err := config.Profiles()
+	if err != nil {
+		return err
+	}
+	if len(profiles) == 0 {
+		fmt.Println("no profile, create one with `hb profile create`")
+		return nil
+	}
+	active := config.Current().Profile
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  NAME\tSETTINGS\tFILES")
+	for _, p := range profiles {
+		mark := " "
+		if p.Name == active {
+			mark = "*"
+		}
+		settings := make([]string, 0, len(p.Values))
+		for k, v := range p.Values {
+			settings = append(settings, k+"="+v)
+		}
+		sort.Strings(settings)
+		fmt.Fprintf(w, "%s %s\t%s\t%s\n", mark, p.Name, strings.Join(settings, " "), strings.Join(p.Files, ","))
+	}
+	return w.Flush()
+}
+
+func runProfileUse(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := checkProfile(name); err != nil {
+		return err
+	}
+	return edit(func(f *config.File) error {
+		f.Values["profile"] = name
+		return nil
+	})
+}
+
+// checkProfile fails unless profile `name` is defined in a configuration file.
+func checkProfile(name string) error {
+	profiles, err := config.Profiles()
+	if err != nil {
+		return err
+	}
+	for _, p := range profiles {
+		if p.Name == name {
+			return nil
+		}
+	}
+	return errs.New(errs.Validation, "unknown profile %s, see `hb profile list`", name)
+}
+
+func runProfileCreate(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	values := map[string]string{}
+	for key, v := range profileValues {
+		if *v == "" {
+			continue
+		}
+		value, err := config.LookupKey(key).Validate(*v)
+		if err != nil {
+			return err
+		}
+		values[key] = value
+	}
+	if len(values) == 0 {
+		return errs.New(errs.Validation, "empty profile, set at least one of --%s", strings.Join(config.ProfileKeys, ", --"))
+	}
+	return edit(func(f *config.File) error {
+		if _, ok := f.Profiles[name]; ok && !force {
+			return errs.New(errs.Validation, "profile %s already exists, use --force to replace it", name)
+		}
+		f.Profiles[name] = values
+		if activate {
+			f.Values["profile"] = name
+		}
+		return nil
+	})
+}
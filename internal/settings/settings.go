@@ -0,0 +1,215 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package settings
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/winc-link/hummingbird-cli/config"
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+	"github.com/winc-link/hummingbird-cli/utility"
+)
+
+var CmdConfig = &cobra.Command{
+	Use:   "config",
+	Short: "read and write the hb configuration.",
+	Long: `read and write the hb configuration.
+
+Settings are merged from, in increasing precedence: the defaults,
+/etc/hb/config.yaml, ~/.config/hb/config.yaml, .hb/config.yaml of the current
+project, the HB_* environment variables and the flags.`,
+}
+
+var cmdGet = &cobra.Command{
+	Use:     "get <key>",
+	Example: "hb config get registry --show-origin",
+	Short:   "print the value of a key.",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runGet,
+}
+
+var cmdSet = &cobra.Command{
+	Use:     "set <key> <value>",
+	Example: "hb config set module-prefix git.company.com/iot/drivers",
+	Short:   "set a key in a configuration file.",
+	Args:    cobra.ExactArgs(2),
+	RunE:    runSet,
+}
+
+var cmdUnset = &cobra.Command{
+	Use:     "unset <key>",
+	Example: "hb config unset registry --project",
+	Short:   "remove a key from a configuration file.",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runUnset,
+}
+
+var cmdList = &cobra.Command{
+	Use:     "list",
+	Example: "hb config list --show-origin",
+	Short:   "list the keys and their values.",
+	Args:    cobra.NoArgs,
+	RunE:    runList,
+}
+
+var (
+	showOrigin bool
+	all        bool
+	system     bool
+	project    bool
+)
+
+func init() {
+	cmdGet.Flags().BoolVar(&showOrigin, "show-origin", showOrigin, "print where the value comes from")
+	cmdList.Flags().BoolVar(&showOrigin, "show-origin", showOrigin, "print where the values come from")
+	cmdList.Flags().BoolVar(&all, "all", all, "list the keys without a value too, with their description")
+	for _, c := range []*cobra.Command{cmdSet, cmdUnset} {
+		c.Flags().BoolVar(&system, "system", system, "edit "+config.SystemFile()+" instead of the user file")
+		c.Flags().BoolVar(&project, "project", project, "edit the project file "+config.ProjectFile+" instead of the user file")
+		c.MarkFlagsMutuallyExclusive("system", "project")
+	}
+
+	CmdConfig.AddCommand(cmdGet, cmdSet, cmdUnset, cmdList)
+}
+
+func runGet(cmd *cobra.Command, args []string) error {
+	if _, err := lookupKey(args[0]); err != nil {
+		return err
+	}
+	s, ok := config.Current().Lookup(args[0])
+	if !ok {
+		return fmt.Errorf("%s is not set", args[0])
+	}
+	if showOrigin {
+		fmt.Printf("%s\t%s\n", s.Origin, s.Value)
+	} else {
+		fmt.Println(s.Value)
+	}
+	return nil
+}
+
+func runSet(cmd *cobra.Command, args []string) error {
+	k, err := lookupKey(args[0])
+	if err != nil {
+		return err
+	}
+	value, err := k.Validate(args[1])
+	if err != nil {
+		return err
+	}
+	if k.Name == "profile" {
+		if err = checkProfile(value); err != nil {
+			return err
+		}
+	}
+	return edit(func(f *config.File) error {
+		f.Values[k.Name] = value
+		return nil
+	})
+}
+
+func runUnset(cmd *cobra.Command, args []string) error {
+	k, err := lookupKey(args[0])
+	if err != nil {
+		return err
+	}
+	return edit(func(f *config.File) error {
+		delete(f.Values, k.Name)
+		return nil
+	})
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	c := config.Current()
+	if !all {
+		for _, s := range c.Settings() {
+			if showOrigin {
+				fmt.Printf("%s\t%s=%s\n", s.Origin, s.Key, s.Value)
+			} else {
+				fmt.Printf("%s=%s\n", s.Key, s.Value)
+			}
+		}
+		for _, section := range []struct {
+			name     string
+			settings []config.Setting
+		}{{"rewrites", c.RewriteSettings()}, {"tokens", c.TokenSettings()}} {
+			for _, s := range section.settings {
+				if showOrigin {
+					fmt.Printf("%s\t%s.%s=%s\n", s.Origin, section.name, s.Key, s.Value)
+				} else {
+					fmt.Printf("%s.%s=%s\n", section.name, s.Key, s.Value)
+				}
+			}
+		}
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if showOrigin {
+		fmt.Fprintln(w, "KEY\tVALUE\tORIGIN\tDESCRIPTION")
+	} else {
+		fmt.Fprintln(w, "KEY\tVALUE\tDESCRIPTION")
+	}
+	for _, k := range config.Keys {
+		s, _ := c.Lookup(k.Name)
+		if showOrigin {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", k.Name, s.Value, s.Origin, k.Description)
+		} else {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", k.Name, s.Value, k.Description)
+		}
+	}
+	return w.Flush()
+}
+
+func lookupKey(name string) (*config.Key, error) {
+	k := config.LookupKey(name)
+	if k == nil {
+		return nil, errs.New(errs.Validation, "unknown key %s, see `hb config list --all`", name)
+	}
+	return k, nil
+}
+
+// edit applies `change` to the configuration file chosen by the flags.
+func edit(change func(f *config.File) error) error {
+	path, err := configFile()
+	if err != nil {
+		return err
+	}
+	f, err := config.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err = change(f); err != nil {
+		return err
+	}
+	if err = config.WriteFile(path, f); err != nil {
+		return fmt.Errorf("write %s failed: %w", path, err)
+	}
+	utility.Resultf("%s updated", path)
+	return nil
+}
+
+// configFile returns the configuration file edited by set and unset.
+func configFile() (string, error) {
+	switch {
+	case system:
+		return config.SystemFile(), nil
+	case project:
+		return config.ProjectFile, nil
+	}
+	return config.UserFile()
+}
@@ -0,0 +1,209 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package template
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+	"github.com/winc-link/hummingbird-cli/internal/layout"
+	"github.com/winc-link/hummingbird-cli/utility"
+)
+
+var cmdUpdate = &cobra.Command{
+	Use:     "update [name...]",
+	Example: "hb template update\nhb template update mqtt --registry Gitee",
+	Short:   "refresh the cached templates from their registries.",
+	RunE:    runUpdate,
+}
+
+var cmdCache = &cobra.Command{
+	Use:   "cache",
+	Short: "inspect and clean the template cache.",
+}
+
+var cmdCacheLs = &cobra.Command{
+	Use:     "ls",
+	Example: "hb template cache ls",
+	Short:   "list the cached templates.",
+	Args:    cobra.NoArgs,
+	RunE:    runCacheLs,
+}
+
+var cmdCachePrune = &cobra.Command{
+	Use:     "prune",
+	Example: "hb template cache prune --older-than 30d",
+	Short:   "remove the cached templates not used for a while.",
+	Args:    cobra.NoArgs,
+	RunE:    runCachePrune,
+}
+
+var (
+	updateRegistry string
+	olderThan      = "30d"
+)
+
+func init() {
+	cmdUpdate.Flags().StringVar(&updateRegistry, "registry", updateRegistry, "only update the sources of this registry")
+	cmdCachePrune.Flags().StringVar(&olderThan, "older-than", olderThan, "age of the entries to remove, e.g. 12h or 30d")
+
+	cmdCache.AddCommand(cmdCacheLs, cmdCachePrune)
+	CmdTemplate.AddCommand(cmdUpdate, cmdCache)
+}
+
+func runUpdate(cmd *cobra.Command, args []string) error {
+	reg, err := layout.LoadRegistry()
+	if err != nil {
+		return fmt.Errorf("load template registry failed: %w", err)
+	}
+	cache, err := layout.OpenCache()
+	if err != nil {
+		return fmt.Errorf("open template cache failed: %w", err)
+	}
+	templates := reg.Templates
+	if len(args) > 0 {
+		templates = nil
+		for _, name := range args {
+			t := reg.Get(name)
+			if t == nil {
+				return errs.New(errs.Validation, "template %s does not exist", name)
+			}
+			templates = append(templates, t)
+		}
+	}
+	var first error
+	failed := 0
+	for _, t := range templates {
+		updated := map[string]bool{}
+		for _, loc := range t.Sources {
+			if updateRegistry != "" && !strings.EqualFold(updateRegistry, loc.Registry) {
+				continue
+			}
+			if updated[strings.ToLower(loc.Registry)] {
+				continue
+			}
+			updated[strings.ToLower(loc.Registry)] = true
+			// The URL of the registry first, then its mirrors, like hb new.
+			all, err := t.Candidates(loc.Registry, true)
+			if err != nil {
+				utility.Warnf("%s (%s): %s", t.Name, loc.Registry, err)
+				continue
+			}
+			var candidates []layout.Candidate
+			for _, c := range all {
+				switch c.Source.(type) {
+				case *layout.GitSource, *layout.HTTPSource:
+					candidates = append(candidates, c)
+				}
+			}
+			if len(candidates) == 0 {
+				continue
+			}
+			utility.Printf("update %s (%s)", t.Name, loc.Registry)
+			_, err = layout.Failover(cmd.Context(), candidates, "", func(ctx context.Context, src layout.TemplateSource, _ string) error {
+				return cache.Update(ctx, src)
+			})
+			if err != nil {
+				utility.Warnf("update %s (%s) failed: %s", t.Name, loc.Registry, err)
+				if first == nil {
+					first = err
+				}
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d updates failed, first: %w", failed, first)
+	}
+	return nil
+}
+
+func runCacheLs(cmd *cobra.Command, args []string) error {
+	cache, err := layout.OpenCache()
+	if err != nil {
+		return fmt.Errorf("open template cache failed: %w", err)
+	}
+	entries, err := cache.Entries()
+	if err != nil {
+		return fmt.Errorf("list template cache failed: %w", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SOURCE\tREVISION\tSIZE\tLAST USED")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Source, shortRevision(e.Revision), humanSize(e.Size), e.LastUsed.Format("2006-01-02 15:04"))
+	}
+	w.Flush()
+	fmt.Printf("cache folder: %s\n", cache.Dir)
+	return nil
+}
+
+func runCachePrune(cmd *cobra.Command, args []string) error {
+	age, err := parseAge(olderThan)
+	if err != nil {
+		return errs.New(errs.Validation, "invalid --older-than: %w", err)
+	}
+	cache, err := layout.OpenCache()
+	if err != nil {
+		return fmt.Errorf("open template cache failed: %w", err)
+	}
+	pruned, err := cache.Prune(age)
+	for _, e := range pruned {
+		utility.Resultf("removed %s %s", e.Source, shortRevision(e.Revision))
+	}
+	if err != nil {
+		return fmt.Errorf("prune template cache failed: %w", err)
+	}
+	utility.Resultf("%d entries removed", len(pruned))
+	return nil
+}
+
+// parseAge parses a time.Duration, also accepting a number of days like "30d".
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func shortRevision(rev string) string {
+	if len(rev) > 12 && !strings.HasPrefix(rev, "(") {
+		return rev[:12]
+	}
+	return rev
+}
+
+func humanSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,258 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package template
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+	"github.com/winc-link/hummingbird-cli/internal/layout"
+	"github.com/winc-link/hummingbird-cli/utility"
+	"golang.org/x/mod/module"
+)
+
+var cmdCreate = &cobra.Command{
+	Use:     "create <project dir>",
+	Example: "hb template create ./modbus-driver --name modbus-inhouse --protocol Modbus-TCP",
+	Short:   "turn an existing driver into a template.",
+	Long: `turn an existing driver into a template.
+
+The project is copied without .git, hb metadata and build outputs, its module
+path is replaced by a placeholder, a starter hb-template.yaml is added and the
+template is registered under the Local registry.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runCreate,
+}
+
+var (
+	createName  string
+	createDir   string
+	placeholder string
+	excludes    []string
+	force       bool
+)
+
+var (
+	// rootOutputs are the folders at the project root never copied into a template.
+	rootOutputs = []string{".hb", ".idea", ".vscode", "build", "bin", "dist"}
+	// buildOutputs are the file names never copied into a template.
+	buildOutputs = []string{".git", "*.exe", "*.test", "*.out", ".DS_Store"}
+)
+
+const starterManifest = `# Manifest of the %s template, see the hb README.
+name: %s
+description: %s
+
+# Questions asked by hb new, available as {{ .name }} in files ending with
+# .tmpl, in files matching a render pattern and in file names.
+variables: []
+#  - name: tls
+#    type: bool
+#    prompt: Enable TLS?
+
+# render: ["configs/*.yaml"]
+
+# Files included only when the condition holds.
+# conditions:
+#  - path: certs
+#    when: .tls
+`
+
+func init() {
+	cmdCreate.Flags().StringVar(&createName, "name", createName, "template name (default the project folder name)")
+	cmdCreate.Flags().StringVar(&createDir, "dir", createDir, "folder of the template (default ~/.hb/templates/<name>)")
+	cmdCreate.Flags().StringVar(&placeholder, "module", placeholder, "placeholder module path of the template (default the template name)")
+	cmdCreate.Flags().StringVarP(&description, "description", "d", description, "template description")
+	cmdCreate.Flags().StringSliceVar(&protocols, "protocol", protocols, "protocol tags")
+	cmdCreate.Flags().StringArrayVar(&excludes, "exclude", excludes, "glob of extra paths to leave out, repeatable")
+	cmdCreate.Flags().BoolVar(&force, "force", force, "replace the template folder if it exists")
+
+	CmdTemplate.AddCommand(cmdCreate)
+}
+
+func runCreate(cmd *cobra.Command, args []string) error {
+	project := args[0]
+	name := createName
+	if name == "" {
+		abs, err := filepath.Abs(project)
+		if err != nil {
+			return err
+		}
+		name = filepath.Base(abs)
+	}
+	modPath, err := layout.ModulePath(project)
+	if err != nil {
+		return errs.Wrap(errs.Validation, fmt.Errorf("read go mod failed: %w", err))
+	}
+	if placeholder == "" {
+		placeholder = name
+	}
+	if err = module.CheckImportPath(placeholder); err != nil {
+		return errs.New(errs.Validation, "invalid placeholder module path, set it with --module: %w", err)
+	}
+
+	out := createDir
+	if out == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		out = filepath.Join(home, ".hb", "templates", name)
+	}
+	if out, err = filepath.Abs(out); err != nil {
+		return err
+	}
+	if _, err = os.Stat(out); err == nil {
+		if !force {
+			return errs.New(errs.Validation, "folder %s already exists, use --force to replace it", out)
+		}
+		if err = os.RemoveAll(out); err != nil {
+			return fmt.Errorf("remove old template failed: %w", err)
+		}
+	}
+
+	utility.Printf("copy %s to %s", project, out)
+	err = layout.CopyDir(project, out, func(rel string) bool {
+		if excluded(rel) {
+			return true
+		}
+		if isExecutable(filepath.Join(project, filepath.FromSlash(rel))) {
+			utility.Printf("skip binary %s", rel)
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return fmt.Errorf("copy project failed: %w", err)
+	}
+
+	changed, err := layout.RewriteModule(out, modPath, placeholder)
+	if err != nil {
+		return fmt.Errorf("rewrite module failed: %w", err)
+	}
+	for _, file := range changed {
+		utility.Printf("rewrite %s", file)
+	}
+
+	manifest := filepath.Join(out, layout.ManifestFile)
+	if _, err = os.Stat(manifest); os.IsNotExist(err) {
+		content := fmt.Sprintf(starterManifest, name, name, description)
+		if err = os.WriteFile(manifest, []byte(content), 0644); err != nil {
+			return fmt.Errorf("write manifest failed: %w", err)
+		}
+		utility.Printf("create %s", layout.ManifestFile)
+	}
+
+	path, reg, err := editableRegistry()
+	if err != nil {
+		return fmt.Errorf("load template registry failed: %w", err)
+	}
+	reg.Put(&layout.Template{
+		Name:        name,
+		Description: description,
+		Protocols:   protocols,
+		Sources:     []layout.Location{{Registry: "Local", URL: out}},
+	})
+	if err = reg.Save(path); err != nil {
+		return fmt.Errorf("save template registry failed: %w", err)
+	}
+	utility.Resultf("template %s created in %s and registered in %s", name, out, path)
+	return nil
+}
+
+// excluded reports whether `rel` is a build output or matches `--exclude`.
+// Patterns without a slash match the base name at any depth.
+func excluded(rel string) bool {
+	for _, name := range rootOutputs {
+		if rel == name {
+			return true
+		}
+	}
+	base := path.Base(rel)
+	for _, pattern := range append(buildOutputs, excludes...) {
+		target := rel
+		if !strings.Contains(pattern, "/") {
+			target = base
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isExecutable reports whether `file` is a compiled binary: ELF, Mach-O or PE.
+func isExecutable(file string) bool {
+	f, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, 8)
+	n, _ := f.Read(head)
+	if n < 4 {
+		return false
+	}
+	if n == 8 && isFatMachO(head) {
+		return true
+	}
+	head = head[:4]
+	for _, magic := range [][]byte{
+		{0x7f, 'E', 'L', 'F'},
+		{0xfe, 0xed, 0xfa, 0xce}, {0xfe, 0xed, 0xfa, 0xcf},
+		{0xce, 0xfa, 0xed, 0xfe}, {0xcf, 0xfa, 0xed, 0xfe},
+	} {
+		if bytes.Equal(head, magic) {
+			return true
+		}
+	}
+	if !bytes.HasPrefix(head, []byte("MZ")) {
+		return false
+	}
+	// A PE file has its "PE\0\0" signature at the offset stored at 0x3c,
+	// a text file merely starting with MZ does not.
+	offset := make([]byte, 4)
+	if _, err = f.ReadAt(offset, 0x3c); err != nil {
+		return false
+	}
+	signature := make([]byte, 4)
+	if _, err = f.ReadAt(signature, int64(binary.LittleEndian.Uint32(offset))); err != nil {
+		return false
+	}
+	return bytes.Equal(signature, []byte("PE\x00\x00"))
+}
+
+// isFatMachO reports whether `head` starts a universal Mach-O binary. Java
+// class files share its magic, there the next word is the class file
+// version instead of a small number of architectures.
+func isFatMachO(head []byte) bool {
+	var archs uint32
+	switch {
+	case bytes.HasPrefix(head, []byte{0xca, 0xfe, 0xba, 0xbe}):
+		archs = binary.BigEndian.Uint32(head[4:])
+	case bytes.HasPrefix(head, []byte{0xbe, 0xba, 0xfe, 0xca}):
+		archs = binary.LittleEndian.Uint32(head[4:])
+	default:
+		return false
+	}
+	return archs > 0 && archs < 20
+}
@@ -0,0 +1,192 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package template
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+	"github.com/winc-link/hummingbird-cli/internal/layout"
+	"github.com/winc-link/hummingbird-cli/utility"
+)
+
+var CmdTemplate = &cobra.Command{
+	Use:   "template",
+	Short: "manage the driver templates offered by hb new.",
+	Long: `manage the driver templates offered by hb new.
+
+Templates are read from ~/.hb/templates.yaml, or the built-in list when it
+does not exist, and from .hb/templates.yaml of the current project.`,
+}
+
+var cmdList = &cobra.Command{
+	Use:     "list",
+	Example: "hb template list",
+	Short:   "list the available templates.",
+	Args:    cobra.NoArgs,
+	RunE:    runList,
+}
+
+var cmdShow = &cobra.Command{
+	Use:     "show <name>",
+	Example: "hb template show mqtt",
+	Short:   "show the definition of a template.",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runShow,
+}
+
+var cmdAdd = &cobra.Command{
+	Use:     "add <name>",
+	Example: "hb template add lora --protocol LoRa --source Github=https://github.com/acme/lora-driver",
+	Short:   "add or replace a template.",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runAdd,
+}
+
+var cmdRemove = &cobra.Command{
+	Use:     "remove <name>",
+	Example: "hb template remove lora",
+	Short:   "remove a template.",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runRemove,
+}
+
+var (
+	project     bool
+	description string
+	protocols   []string
+	sources     []string
+	ref         string
+)
+
+func init() {
+	CmdTemplate.PersistentFlags().BoolVar(&project, "project", project, "edit the project registry "+layout.ProjectRegistryFile+" instead of the user one")
+
+	cmdAdd.Flags().StringVarP(&description, "description", "d", description, "template description")
+	cmdAdd.Flags().StringSliceVar(&protocols, "protocol", protocols, "protocol tags")
+	cmdAdd.Flags().StringArrayVarP(&sources, "source", "s", sources, "registry=url, repeatable, in preference order; the next urls of a registry are its mirrors")
+	cmdAdd.Flags().StringVar(&ref, "ref", ref, "default git branch, tag or commit")
+	_ = cmdAdd.MarkFlagRequired("source")
+
+	CmdTemplate.AddCommand(cmdList, cmdShow, cmdAdd, cmdRemove)
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	reg, err := layout.LoadRegistry()
+	if err != nil {
+		return fmt.Errorf("load template registry failed: %w", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPROTOCOLS\tREGISTRIES\tDESCRIPTION")
+	for _, t := range reg.Templates {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.Name, strings.Join(t.Protocols, ","), strings.Join(t.Registries(), ","), t.Description)
+	}
+	return w.Flush()
+}
+
+func runShow(cmd *cobra.Command, args []string) error {
+	reg, err := layout.LoadRegistry()
+	if err != nil {
+		return fmt.Errorf("load template registry failed: %w", err)
+	}
+	t := reg.Get(args[0])
+	if t == nil {
+		return errs.New(errs.Validation, "template %s does not exist", args[0])
+	}
+	data, err := layout.MarshalYAML(t)
+	if err != nil {
+		return fmt.Errorf("marshal template failed: %w", err)
+	}
+	fmt.Print(string(data))
+	return nil
+}
+
+func runAdd(cmd *cobra.Command, args []string) error {
+	t := &layout.Template{
+		Name:        args[0],
+		Description: description,
+		Protocols:   protocols,
+		Ref:         ref,
+	}
+	for _, s := range sources {
+		registry, url, ok := strings.Cut(s, "=")
+		if !ok || registry == "" || url == "" {
+			return errs.New(errs.Validation, "invalid source %q, expect registry=url", s)
+		}
+		if _, err := layout.ParseSource(url); err != nil {
+			return errs.Wrap(errs.Validation, err)
+		}
+		if loc := location(t, registry); loc != nil {
+			loc.Mirrors = append(loc.Mirrors, url)
+			continue
+		}
+		t.Sources = append(t.Sources, layout.Location{Registry: registry, URL: url})
+	}
+
+	path, reg, err := editableRegistry()
+	if err != nil {
+		return fmt.Errorf("load template registry failed: %w", err)
+	}
+	reg.Put(t)
+	if err = reg.Save(path); err != nil {
+		return fmt.Errorf("save template registry failed: %w", err)
+	}
+	utility.Resultf("template %s saved to %s", t.Name, path)
+	return nil
+}
+
+// location returns the location of `registry` in `t`, or nil.
+func location(t *layout.Template, registry string) *layout.Location {
+	for i := range t.Sources {
+		if strings.EqualFold(t.Sources[i].Registry, registry) {
+			return &t.Sources[i]
+		}
+	}
+	return nil
+}
+
+func runRemove(cmd *cobra.Command, args []string) error {
+	path, reg, err := editableRegistry()
+	if err != nil {
+		return fmt.Errorf("load template registry failed: %w", err)
+	}
+	if !reg.Remove(args[0]) {
+		return errs.New(errs.Validation, "template %s does not exist in %s", args[0], path)
+	}
+	if err = reg.Save(path); err != nil {
+		return fmt.Errorf("save template registry failed: %w", err)
+	}
+	utility.Resultf("template %s removed from %s", args[0], path)
+	return nil
+}
+
+// editableRegistry returns the registry file selected by `--project` and its content.
+// A missing user registry starts from the built-in templates.
+func editableRegistry() (string, *layout.Registry, error) {
+	if project {
+		reg, err := layout.ReadRegistry(layout.ProjectRegistryFile, false)
+		return layout.ProjectRegistryFile, reg, err
+	}
+	path, err := layout.UserRegistryFile()
+	if err != nil {
+		return "", nil, err
+	}
+	reg, err := layout.ReadRegistry(path, true)
+	return path, reg, err
+}
@@ -0,0 +1,148 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+	"github.com/winc-link/hummingbird-cli/internal/layout"
+	"github.com/winc-link/hummingbird-cli/utility"
+)
+
+var cmdValidate = &cobra.Command{
+	Use:     "validate [dir|source|name]",
+	Example: "hb template validate .\nhb template validate mqtt --registry Gitee --format json",
+	Short:   "check that a template generates a valid driver.",
+	Long: `check that a template generates a valid driver.
+
+The template must have a parseable go.mod, use its module path only in
+imports, declare and use every manifest variable, and pass go vet once
+rendered with sample answers. The command exits with status 2 when an error
+is found; warnings do not fail it.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runValidate,
+}
+
+var (
+	validateRegistry string
+	format           = "text"
+	skipVet          bool
+)
+
+// validation is the result of hb template validate, printed by --format json.
+type validation struct {
+	Template string           `json:"template"`
+	Valid    bool             `json:"valid"`
+	Findings []layout.Finding `json:"findings"`
+}
+
+func init() {
+	cmdValidate.Flags().StringVar(&validateRegistry, "registry", validateRegistry, "registry of a template given by name (default its first one)")
+	cmdValidate.Flags().StringVar(&format, "format", format, "output format, text or json")
+	cmdValidate.Flags().BoolVar(&skipVet, "skip-vet", skipVet, "skip the trial render and go vet")
+
+	CmdTemplate.AddCommand(cmdValidate)
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	if format != "text" && format != "json" {
+		return errs.New(errs.Validation, "invalid --format %q, expect text or json", format)
+	}
+	spec := "."
+	if len(args) > 0 {
+		spec = args[0]
+	}
+	src, err := validateSource(spec)
+	if err != nil {
+		return fmt.Errorf("resolve template failed: %w", err)
+	}
+
+	tmp, err := os.MkdirTemp("", "hb-validate-*")
+	if err != nil {
+		return fmt.Errorf("create temp folder failed: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	dir := filepath.Join(tmp, "template")
+	if format == "text" {
+		utility.Print(src.String())
+	}
+	if err = src.Fetch(cmd.Context(), dir); err != nil {
+		return fmt.Errorf("fetch template failed: %w", err)
+	}
+
+	result := validation{Template: src.Spec(), Findings: layout.Validate(dir)}
+	result.Valid = !hasErrors(result.Findings)
+	if result.Valid && !skipVet {
+		// A broken go.mod or manifest already fails; rendering would only repeat it.
+		result.Findings = append(result.Findings, layout.TrialRender(cmd.Context(), dir, filepath.Join(tmp, "render"), "example.com/hb/validate")...)
+		result.Valid = !hasErrors(result.Findings)
+	}
+	if result.Findings == nil {
+		result.Findings = []layout.Finding{}
+	}
+
+	if format == "json" {
+		data, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		for _, f := range result.Findings {
+			fmt.Println(f)
+		}
+		if result.Valid {
+			fmt.Printf("template %s is valid\n", result.Template)
+		} else {
+			fmt.Printf("template %s is invalid\n", result.Template)
+		}
+	}
+	if !result.Valid {
+		return errs.Reported(errs.Validation, fmt.Errorf("template %s is invalid", result.Template))
+	}
+	return nil
+}
+
+// validateSource resolves `spec` as a local path or a --from spec, then as the
+// name of a registry template.
+func validateSource(spec string) (layout.TemplateSource, error) {
+	if _, err := os.Stat(spec); err == nil {
+		return layout.ParseSource(spec)
+	}
+	reg, err := layout.LoadRegistry()
+	if err != nil {
+		return nil, err
+	}
+	t := reg.Get(spec)
+	if t == nil {
+		return layout.ParseSource(spec)
+	}
+	registry := validateRegistry
+	if registry == "" && len(t.Sources) > 0 {
+		registry = t.Sources[0].Registry
+	}
+	return t.Source(registry)
+}
+
+func hasErrors(findings []layout.Finding) bool {
+	for _, f := range findings {
+		if f.Severity == layout.SeverityError {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package upgrade
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+
+	"github.com/winc-link/hummingbird-cli/internal/executor"
+	"github.com/winc-link/hummingbird-cli/internal/new"
+)
+
+// summary lists the files touched by a merge, relative to the project.
+type summary struct {
+	updated   []string
+	added     []string
+	removed   []string
+	merged    []string
+	conflicts []string
+}
+
+func (s *summary) print() {
+	for _, group := range []struct {
+		title string
+		files []string
+	}{
+		{"updated", s.updated},
+		{"added", s.added},
+		{"removed", s.removed},
+		{"merged", s.merged},
+		{"conflict", s.conflicts},
+	} {
+		for _, f := range group.files {
+			fmt.Printf("%-9s %s\n", group.title, f)
+		}
+	}
+	fmt.Printf("%d updated, %d added, %d removed, %d merged, %d conflicts\n",
+		len(s.updated), len(s.added), len(s.removed), len(s.merged), len(s.conflicts))
+}
+
+// merge applies to `project` the changes between the template generations
+// `base` and `theirs`.
+func merge(ctx context.Context, project, base, theirs string) (*summary, error) {
+	files := map[string]bool{}
+	for _, dir := range []string{project, base, theirs} {
+		if err := listFiles(dir, files); err != nil {
+			return nil, err
+		}
+	}
+	names := make([]string, 0, len(files))
+	for rel := range files {
+		names = append(names, rel)
+	}
+	sort.Strings(names)
+
+	s := &summary{}
+	for _, rel := range names {
+		if err := mergeFile(ctx, s, rel, project, base, theirs); err != nil {
+			return nil, fmt.Errorf("%s: %w", rel, err)
+		}
+	}
+	return s, nil
+}
+
+func mergeFile(ctx context.Context, s *summary, rel, project, base, theirs string) error {
+	path := filepath.Join(project, filepath.FromSlash(rel))
+	oursData, oursOK := readFile(path)
+	baseData, baseOK := readFile(filepath.Join(base, filepath.FromSlash(rel)))
+	theirsPath := filepath.Join(theirs, filepath.FromSlash(rel))
+	theirsData, theirsOK := readFile(theirsPath)
+
+	switch {
+	case baseOK == theirsOK && bytes.Equal(baseData, theirsData):
+		// The template did not change the file.
+		return nil
+	case oursOK == theirsOK && bytes.Equal(oursData, theirsData):
+		// The project already has the change.
+		return nil
+	case !theirsOK:
+		if oursOK && bytes.Equal(oursData, baseData) {
+			s.removed = append(s.removed, rel)
+			return os.Remove(path)
+		}
+		if oursOK {
+			s.conflicts = append(s.conflicts, rel+" (removed by the template, changed in the project)")
+		}
+		return nil
+	case !oursOK:
+		if !baseOK {
+			s.added = append(s.added, rel)
+			return copyFile(theirsPath, path)
+		}
+		s.conflicts = append(s.conflicts, rel+" (changed by the template, removed from the project, see "+rel+".hb-new)")
+		return copyFile(theirsPath, path+".hb-new")
+	case baseOK && bytes.Equal(oursData, baseData):
+		s.updated = append(s.updated, rel)
+		return copyFile(theirsPath, path)
+	}
+
+	if isBinary(oursData) || isBinary(theirsData) {
+		s.conflicts = append(s.conflicts, rel+" (binary, see "+rel+".hb-new)")
+		return copyFile(theirsPath, path+".hb-new")
+	}
+	conflicts, err := mergeText(ctx, path, baseData, theirsPath)
+	if err != nil {
+		return err
+	}
+	if conflicts {
+		s.conflicts = append(s.conflicts, rel)
+	} else {
+		s.merged = append(s.merged, rel)
+	}
+	return nil
+}
+
+// mergeText three-way merges into `path` with `git merge-file` and reports
+// whether conflict markers were left.
+func mergeText(ctx context.Context, path string, base []byte, theirsPath string) (bool, error) {
+	tmp, err := os.CreateTemp("", "hb-merge-base-")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(base); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err = tmp.Close(); err != nil {
+		return false, err
+	}
+
+	opts := executor.Options{Timeout: executor.LocalTimeout}
+	_, err = executor.Run(ctx, opts, "git", "merge-file", "-L", "project", "-L", "template base", "-L", "template new", path, tmp.Name(), theirsPath)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 && exitErr.ExitCode() < 128 {
+		return true, nil
+	}
+	return false, err
+}
+
+// listFiles adds the files of `dir` to `files`, skipping `.git` and the lock file.
+func listFiles(dir string, files map[string]bool) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		rel = filepath.ToSlash(rel)
+		if info.IsDir() {
+			if rel == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rel != new.LockFile && info.Mode().IsRegular() {
+			files[rel] = true
+		}
+		return nil
+	})
+}
+
+func readFile(path string) ([]byte, bool) {
+	data, err := os.ReadFile(path)
+	return data, err == nil
+}
+
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, info.Mode().Perm())
+}
+
+func isBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) != -1
+}
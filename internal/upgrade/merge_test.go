@@ -0,0 +1,117 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package upgrade
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergeFile(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git merge-file needs git")
+	}
+	const absent = "\x01absent"
+	tests := []struct {
+		name               string
+		ours, base, theirs string
+		group              string
+		want               string
+		// hbNew is the expected content of <file>.hb-new, "" if none.
+		hbNew string
+	}{
+		{name: "unchanged by the template", ours: "b\n", base: "a\n", theirs: "a\n", want: "b\n"},
+		{name: "change already applied", ours: "b\n", base: "a\n", theirs: "b\n", want: "b\n"},
+		{name: "untouched in the project", ours: "a\n", base: "a\n", theirs: "b\n", group: "updated", want: "b\n"},
+		{name: "added by the template", ours: absent, base: absent, theirs: "n\n", group: "added", want: "n\n"},
+		{name: "removed by the template", ours: "a\n", base: "a\n", theirs: absent, group: "removed", want: absent},
+		{name: "removed by the template, changed in the project", ours: "b\n", base: "a\n", theirs: absent, group: "conflicts", want: "b\n"},
+		{name: "removed from the project", ours: absent, base: "a\n", theirs: "b\n", group: "conflicts", want: absent, hbNew: "b\n"},
+		{
+			name: "both changed, other lines", group: "merged",
+			ours:   "1 project\n2\n3\n4\n5\n",
+			base:   "1\n2\n3\n4\n5\n",
+			theirs: "1\n2\n3\n4\n5 template\n",
+			want:   "1 project\n2\n3\n4\n5 template\n",
+		},
+		{
+			name: "both changed, same line", group: "conflicts",
+			ours:   "1 project\n",
+			base:   "1\n",
+			theirs: "1 template\n",
+			want:   "<<<<<<<",
+		},
+		{name: "binary", ours: "b\x00", base: "a\x00", theirs: "c\x00", group: "conflicts", want: "b\x00", hbNew: "c\x00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dirs := map[string]string{}
+			for name, content := range map[string]string{"project": tt.ours, "base": tt.base, "theirs": tt.theirs} {
+				dirs[name] = t.TempDir()
+				if content != absent {
+					if err := os.WriteFile(filepath.Join(dirs[name], "main.go"), []byte(content), 0644); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			s := &summary{}
+			if err := mergeFile(context.Background(), s, "main.go", dirs["project"], dirs["base"], dirs["theirs"]); err != nil {
+				t.Fatalf("mergeFile failed: %v", err)
+			}
+
+			if got := group(s, "main.go"); got != tt.group {
+				t.Errorf("listed as %q, want %q", got, tt.group)
+			}
+			path := filepath.Join(dirs["project"], "main.go")
+			data, err := os.ReadFile(path)
+			switch {
+			case tt.want == absent:
+				if err == nil {
+					t.Errorf("file kept:\n%s", data)
+				}
+			case err != nil:
+				t.Errorf("file missing: %v", err)
+			case tt.want == "<<<<<<<":
+				if !strings.Contains(string(data), tt.want) {
+					t.Errorf("no conflict markers:\n%s", data)
+				}
+			case string(data) != tt.want:
+				t.Errorf("file =\n%q\nwant\n%q", data, tt.want)
+			}
+			data, err = os.ReadFile(path + ".hb-new")
+			if tt.hbNew == "" && err == nil || tt.hbNew != "" && string(data) != tt.hbNew {
+				t.Errorf("main.go.hb-new = %q, want %q", data, tt.hbNew)
+			}
+		})
+	}
+}
+
+// group returns the group of the summary listing `rel`, "" if none.
+func group(s *summary, rel string) string {
+	for name, files := range map[string][]string{
+		"updated": s.updated, "added": s.added, "removed": s.removed, "merged": s.merged, "conflicts": s.conflicts,
+	} {
+		for _, f := range files {
+			if f == rel || strings.HasPrefix(f, rel+" ") {
+				return name
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package upgrade
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/winc-link/hummingbird-cli/internal/errs"
+	"github.com/winc-link/hummingbird-cli/internal/layout"
+	"github.com/winc-link/hummingbird-cli/internal/new"
+	"github.com/winc-link/hummingbird-cli/utility"
+)
+
+var CmdUpgradeProject = &cobra.Command{
+	Use:     "upgrade-project",
+	Example: "hb upgrade-project --ref v1.2.0",
+	Short:   "merge the changes of the template into the project.",
+	Long: `merge the changes of the template into the project.
+
+The template version recorded in .hb/project.lock and the requested one are
+both generated with the recorded answers, and the difference between them is
+three-way merged into the project. Conflicting changes are left with conflict
+markers, or as <file>.hb-new for binary and deleted files.`,
+	Args: cobra.NoArgs,
+	RunE: run,
+}
+
+var (
+	projectDir = "."
+	ref        string
+	base       string
+	noCache    bool
+)
+
+func init() {
+	CmdUpgradeProject.Flags().StringVar(&projectDir, "dir", projectDir, "project folder")
+	CmdUpgradeProject.Flags().StringVar(&ref, "ref", ref, "git branch, tag or commit to upgrade to (default the template default branch)")
+	CmdUpgradeProject.Flags().StringVar(&base, "base", base, "template source of the version the project was generated from, when it cannot be fetched again from the lock")
+	CmdUpgradeProject.Flags().BoolVar(&noCache, "no-cache", noCache, "fetch the template from its source instead of the template cache")
+}
+
+func run(cmd *cobra.Command, args []string) error {
+	new.SetNoCache(noCache)
+	lock, err := new.ReadLock(projectDir)
+	if err != nil {
+		return errs.Wrap(errs.Validation, fmt.Errorf("read project lock failed: %w", err))
+	}
+	if lock.Answers == nil || lock.Template.Source == "" {
+		return errs.New(errs.Validation, "%s does not record the template of the project", new.LockFile)
+	}
+
+	work, err := os.MkdirTemp("", "hb-upgrade-")
+	if err != nil {
+		return fmt.Errorf("create work folder failed: %w", err)
+	}
+	defer os.RemoveAll(work)
+
+	baseSrc, err := baseSource(lock)
+	if err != nil {
+		return fmt.Errorf("resolve template failed: %w", err)
+	}
+	baseDir := filepath.Join(work, "base", lock.Answers.Name)
+	baseLock, err := new.Regenerate(cmd.Context(), baseDir, lock.Answers, baseSrc)
+	if err != nil {
+		return err
+	}
+	if base == "" && lock.Template.Commit != "" && baseLock.Template.Commit != lock.Template.Commit {
+		return errs.New(errs.Validation, "template %s changed since the project was generated, pass the original version with --base", lock.Template.Source)
+	}
+
+	newSrc, err := layout.ParseSource(lock.Template.Source)
+	if err != nil {
+		return fmt.Errorf("resolve template failed: %w", err)
+	}
+	if g, ok := newSrc.(*layout.GitSource); ok {
+		g.Ref = ref
+	}
+	newDir := filepath.Join(work, "new", lock.Answers.Name)
+	newLock, err := new.Regenerate(cmd.Context(), newDir, lock.Answers, newSrc)
+	if err != nil {
+		return err
+	}
+	if newLock.Template.Commit != "" && newLock.Template.Commit == lock.Template.Commit {
+		utility.Result("project is already up to date")
+		return nil
+	}
+
+	s, err := merge(cmd.Context(), projectDir, baseDir, newDir)
+	if err != nil {
+		return fmt.Errorf("merge template failed: %w", err)
+	}
+	newLock.Answers.Overwrite = lock.Answers.Overwrite
+	// The project now follows the version it was upgraded to.
+	newLock.Answers.Ref = ref
+	if err = newLock.Save(projectDir); err != nil {
+		return fmt.Errorf("write project lock failed: %w", err)
+	}
+	s.print()
+	if len(s.conflicts) > 0 {
+		utility.Print("resolve the conflicts, then run `go mod tidy`")
+	} else {
+		utility.Print("run `go mod tidy` and review the changes")
+	}
+	return nil
+}
+
+// baseSource returns the source of the template version the project was generated from.
+func baseSource(lock *new.Lock) (layout.TemplateSource, error) {
+	if base != "" {
+		return layout.ParseSource(base)
+	}
+	src, err := layout.ParseSource(lock.Template.Source)
+	if err != nil {
+		return nil, err
+	}
+	if g, ok := src.(*layout.GitSource); ok {
+		g.Ref = lock.Template.Commit
+		if g.Ref == "" {
+			g.Ref = lock.Template.Ref
+		}
+	}
+	return src, nil
+}
@@ -0,0 +1,98 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package upgrade
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/winc-link/hummingbird-cli/internal/layout"
+	"github.com/winc-link/hummingbird-cli/internal/new"
+)
+
+func TestUpgradeRecordsRef(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("the template is a git repository")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("XDG_CACHE_HOME", home)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, v := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(v, "hb")
+	}
+	for _, v := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(v, "hb@example.com")
+	}
+
+	tpl := filepath.Join(t.TempDir(), "driver.git")
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tpl
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(tpl, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(tpl, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	git("init", "-q")
+	write("go.mod", "module driver\n\ngo 1.20\n")
+	write("main.go", "package main\n\nfunc main() {}\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "v1")
+	git("tag", "v1")
+	write("main.go", "package main\n\nfunc main() { println(2) }\n")
+	git("commit", "-q", "-am", "v2")
+	git("tag", "v2")
+
+	dir := filepath.Join(t.TempDir(), "demo")
+	answers := &new.Answers{Name: "demo", Module: "example.com/demo", From: tpl, Ref: "v1"}
+	lock, err := new.Regenerate(context.Background(), dir, answers, &layout.GitSource{URL: tpl, Ref: "v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = lock.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(d, r string, n bool) { projectDir, ref, noCache = d, r, n }(projectDir, ref, noCache)
+	projectDir, ref, noCache = dir, "v2", true
+	CmdUpgradeProject.SetContext(context.Background())
+	if err = run(CmdUpgradeProject, nil); err != nil {
+		t.Fatal(err)
+	}
+	upgraded, err := new.ReadLock(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if upgraded.Template.Commit == lock.Template.Commit {
+		t.Errorf("template commit is still %s", lock.Template.Commit)
+	}
+	if upgraded.Answers.Ref != "v2" {
+		t.Errorf("answers ref is %q, want v2", upgraded.Answers.Ref)
+	}
+}
@@ -15,13 +15,14 @@
 package main
 
 import (
-	"fmt"
+	"os"
+
 	"github.com/winc-link/hummingbird-cli/cmd/hbird"
+	"github.com/winc-link/hummingbird-cli/internal/errs"
 )
 
 func main() {
-	err := hbird.Execute()
-	if err != nil {
-		fmt.Println("execute error: ", err.Error())
+	if err := hbird.Execute(); err != nil {
+		os.Exit(errs.ExitCode(err))
 	}
 }
@@ -14,23 +14,19 @@
 
 package utility
 
-import (
-	"github.com/gogf/gf/v2/os/gcmd"
-	"github.com/gogf/gf/v2/os/genv"
-)
+// EnvName answers every question with its default when set to a true value,
+// like the --yes flag.
+const EnvName = "HB_YES"
 
-const (
-	EnvName = "GF_CLI_ALL_YES"
-)
+var allYes = EnvBool(EnvName)
 
-// Init initializes the package manually.
-func Init() {
-	if gcmd.GetOpt("y") != nil {
-		genv.MustSet(EnvName, "1")
-	}
+// Init sets whether every question is answered with its default, from the
+// --yes flag.
+func Init(yes bool) {
+	allYes = yes
 }
 
 // Check checks whether option allow all yes for command.
 func Check() bool {
-	return genv.Get(EnvName).String() == "1"
+	return allYes
 }
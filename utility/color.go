@@ -0,0 +1,49 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package utility
+
+import (
+	"os"
+	"regexp"
+
+	"github.com/AlecAivazis/survey/v2/core"
+)
+
+// NoColorEnv disables colors when set to a true value, like --no-color. The
+// common NO_COLOR variable is honored as well.
+const NoColorEnv = "HB_NO_COLOR"
+
+var (
+	color = os.Getenv("NO_COLOR") == "" && !EnvBool(NoColorEnv)
+	ansi  = regexp.MustCompile("\x1b\\[[0-9;]*m")
+)
+
+func init() {
+	core.DisableColor = !color
+}
+
+// SetColor enables or disables colored output, prompts included.
+func SetColor(enabled bool) {
+	color = enabled
+	core.DisableColor = !enabled
+}
+
+// Colored returns `s` as is, or without its ANSI colors when they are disabled.
+func Colored(s string) string {
+	if color {
+		return s
+	}
+	return ansi.ReplaceAllString(s, "")
+}
@@ -15,65 +15,314 @@
 package utility
 
 import (
-	"context"
-	"github.com/gogf/gf/v2/os/gcmd"
-	"github.com/gogf/gf/v2/os/genv"
-	"github.com/gogf/gf/v2/os/glog"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Level is the severity of a log record.
+type Level int
+
+const (
+	LevelTrace Level = iota
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
 )
 
+var levelNames = []string{"trace", "debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelTrace || l > LevelError {
+		return "unknown"
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses a level name: trace, debug, info, warn or error.
+func ParseLevel(name string) (Level, error) {
+	for i, n := range levelNames {
+		if strings.EqualFold(name, n) {
+			return Level(i), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q, expect one of %s", name, strings.Join(levelNames, ", "))
+}
+
+// Log formats.
 const (
-	headerPrintEnvName = "GF_CLI_MLOG_HEADER"
+	FormatText = "text"
+	FormatJSON = "json"
 )
 
-var (
-	ctx    = context.TODO()
-	logger = glog.New()
+const (
+	// DebugEnv enables debug output when set to a true value, like --debug.
+	DebugEnv = "HB_DEBUG"
+	// QuietEnv hides informational output when set to a true value, like --quiet.
+	QuietEnv = "HB_QUIET"
+	// LevelEnv sets the log level, like --log-level.
+	LevelEnv = "HB_LOG_LEVEL"
+	// FormatEnv sets the log format, like --log-format.
+	FormatEnv = "HB_LOG_FORMAT"
+	// FileEnv sets the log file, like --log-file.
+	FileEnv = "HB_LOG_FILE"
 )
 
+// Fields are the structured attributes of a log record.
+type Fields map[string]interface{}
+
+// logger writes the records of hb: to stderr from its level, and to the log
+// file, if any, from trace level. The results of a command, written with
+// Result, go to stdout instead, so they can be redirected apart from the logs.
+var logger = struct {
+	sync.Mutex
+	level  Level
+	format string
+	out    io.Writer
+	result io.Writer
+	file   *os.File
+}{level: LevelInfo, format: FormatText, out: os.Stderr, result: os.Stdout}
+
 func init() {
-	logger.SetStack(false)
-	if genv.Get(headerPrintEnvName).String() == "1" {
-		logger.SetHeaderPrint(true)
-	} else {
-		logger.SetHeaderPrint(false)
+	if EnvBool(DebugEnv) {
+		logger.level = LevelDebug
+	} else if EnvBool(QuietEnv) {
+		logger.level = LevelWarn
+	}
+	if l, err := ParseLevel(os.Getenv(LevelEnv)); err == nil {
+		logger.level = l
+	}
+	if os.Getenv(FormatEnv) == FormatJSON {
+		logger.format = FormatJSON
+	}
+}
+
+// SetLevel sets the lowest level written to stderr.
+func SetLevel(level Level) {
+	logger.Lock()
+	defer logger.Unlock()
+	logger.level = level
+}
+
+// SetFormat sets the format of the records, text or json.
+func SetFormat(format string) error {
+	if format != FormatText && format != FormatJSON {
+		return fmt.Errorf("unknown log format %q, expect text or json", format)
+	}
+	logger.Lock()
+	defer logger.Unlock()
+	logger.format = format
+	return nil
+}
+
+// Format returns the format of the records.
+func Format() string {
+	return logger.format
+}
+
+// SetLogFile appends every record to `path` as well, whatever the level.
+func SetLogFile(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("open log file failed: %w", err)
+	}
+	logger.Lock()
+	defer logger.Unlock()
+	if logger.file != nil {
+		logger.file.Close()
+	}
+	logger.file = f
+	return nil
+}
+
+// CloseLog closes the log file, if any.
+func CloseLog() {
+	logger.Lock()
+	defer logger.Unlock()
+	if logger.file != nil {
+		logger.file.Close()
+		logger.file = nil
+	}
+}
+
+// Quiet reports whether informational output is hidden.
+func Quiet() bool {
+	return logger.level > LevelInfo
+}
+
+// Enabled reports whether records of `level` are written to stderr.
+func Enabled(level Level) bool {
+	return level >= logger.level
+}
+
+// Log writes a record of `level` with `fields`. Secrets in the message and
+// in the string fields are redacted.
+func Log(level Level, fields Fields, msg string) {
+	logger.Lock()
+	defer logger.Unlock()
+	if level < logger.level && logger.file == nil {
+		return
+	}
+	msg = Redact(msg)
+	for k, v := range fields {
+		if s, ok := v.(string); ok {
+			fields[k] = Redact(s)
+		}
 	}
-	if gcmd.GetOpt("debug") != nil || gcmd.GetOpt("gf.debug") != nil {
-		logger.SetDebug(true)
+	now := time.Now()
+	if level >= logger.level {
+		if logger.format == FormatJSON {
+			fmt.Fprintln(logger.out, jsonRecord(now, level, fields, msg))
+		} else {
+			fmt.Fprintln(logger.out, textRecord(level, msg))
+		}
+	}
+	writeFile(now, level, fields, msg)
+}
+
+// writeFile appends a record to the log file, if any. The logger is locked.
+func writeFile(now time.Time, level Level, fields Fields, msg string) {
+	if logger.file == nil {
+		return
+	}
+	if logger.format == FormatJSON {
+		fmt.Fprintln(logger.file, jsonRecord(now, level, fields, msg))
 	} else {
-		logger.SetDebug(false)
+		fmt.Fprintf(logger.file, "%s %-5s %s%s\n", now.Format(time.RFC3339Nano), strings.ToUpper(level.String()), ansi.ReplaceAllString(msg, ""), textFields(fields))
 	}
 }
 
-// SetHeaderPrint enables/disables header printing to stdout.
-func SetHeaderPrint(enabled bool) {
-	logger.SetHeaderPrint(enabled)
-	if enabled {
-		_ = genv.Set(headerPrintEnvName, "1")
+// Result writes `msg`, a result of the command like the created project, to
+// stdout whatever the level, and to the log file at info level. Colors are
+// kept only when stdout is a terminal.
+func Result(msg string) {
+	logger.Lock()
+	defer logger.Unlock()
+	msg = Redact(msg)
+	if f, ok := logger.result.(*os.File); ok && color && term.IsTerminal(int(f.Fd())) {
+		fmt.Fprintln(logger.result, msg)
 	} else {
-		_ = genv.Set(headerPrintEnvName, "0")
+		fmt.Fprintln(logger.result, ansi.ReplaceAllString(msg, ""))
 	}
+	writeFile(time.Now(), LevelInfo, nil, msg)
 }
 
-func Print(v ...interface{}) {
-	logger.Print(ctx, v...)
+// textRecord renders a record for humans: the message alone at info level,
+// prefixed by its level otherwise.
+func textRecord(level Level, msg string) string {
+	switch level {
+	case LevelInfo:
+		return Colored(msg)
+	case LevelWarn:
+		return "Warning: " + Colored(msg)
+	case LevelError:
+		return "Error: " + Colored(msg)
+	}
+	return level.String() + ": " + Colored(msg)
 }
 
-func Printf(format string, v ...interface{}) {
-	logger.Printf(ctx, format, v...)
+func textFields(fields Fields) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, fields[k])
+	}
+	return b.String()
 }
 
-func Fatal(v ...interface{}) {
-	logger.Fatal(ctx, v...)
+// jsonRecord renders a record as a JSON line with its time, level, message
+// and fields.
+func jsonRecord(t time.Time, level Level, fields Fields, msg string) string {
+	record := make(map[string]interface{}, len(fields)+3)
+	for k, v := range fields {
+		record[k] = v
+	}
+	record["time"] = t.Format(time.RFC3339Nano)
+	record["level"] = level.String()
+	record["msg"] = ansi.ReplaceAllString(msg, "")
+	data, err := json.Marshal(record)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"time": t.Format(time.RFC3339Nano), "level": level.String(), "msg": msg})
+	}
+	return string(data)
 }
 
-func Fatalf(format string, v ...interface{}) {
-	logger.Fatalf(ctx, format, v...)
+// Step logs the start of step `name` at trace level and returns the function
+// logging its duration at debug level once done.
+func Step(name string) func() {
+	start := time.Now()
+	Log(LevelTrace, Fields{"step": name}, name+" started")
+	return func() {
+		elapsed := time.Since(start)
+		Log(LevelDebug, Fields{"step": name, "duration_ms": elapsed.Milliseconds()},
+			fmt.Sprintf("%s done in %s", name, elapsed.Round(time.Millisecond)))
+	}
+}
+
+func Trace(v ...interface{}) {
+	Log(LevelTrace, nil, fmt.Sprint(v...))
+}
+
+func Tracef(format string, v ...interface{}) {
+	Log(LevelTrace, nil, fmt.Sprintf(format, v...))
 }
 
 func Debug(v ...interface{}) {
-	logger.Debug(ctx, v...)
+	Log(LevelDebug, nil, fmt.Sprint(v...))
 }
 
 func Debugf(format string, v ...interface{}) {
-	logger.Debugf(ctx, format, v...)
+	Log(LevelDebug, nil, fmt.Sprintf(format, v...))
+}
+
+func Print(v ...interface{}) {
+	Log(LevelInfo, nil, fmt.Sprint(v...))
+}
+
+func Printf(format string, v ...interface{}) {
+	Log(LevelInfo, nil, fmt.Sprintf(format, v...))
+}
+
+func Resultf(format string, v ...interface{}) {
+	Result(fmt.Sprintf(format, v...))
+}
+
+func Warn(v ...interface{}) {
+	Log(LevelWarn, nil, fmt.Sprint(v...))
+}
+
+func Warnf(format string, v ...interface{}) {
+	Log(LevelWarn, nil, fmt.Sprintf(format, v...))
+}
+
+func Error(v ...interface{}) {
+	Log(LevelError, nil, fmt.Sprint(v...))
+}
+
+func Errorf(format string, v ...interface{}) {
+	Log(LevelError, nil, fmt.Sprintf(format, v...))
+}
+
+func Fatal(v ...interface{}) {
+	Error(v...)
+	CloseLog()
+	os.Exit(1)
+}
+
+func Fatalf(format string, v ...interface{}) {
+	Errorf(format, v...)
+	CloseLog()
+	os.Exit(1)
 }
@@ -0,0 +1,38 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package utility
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestResult(t *testing.T) {
+	var out, result bytes.Buffer
+	defer func(o, r io.Writer) {
+		logger.out, logger.result = o, r
+	}(logger.out, logger.result)
+	logger.out, logger.result = &out, &result
+
+	Printf("copy %s", "driver")
+	Resultf("\x1b[36m%s\x1b[0m created", "demo")
+	if got := out.String(); got != "copy driver\n" {
+		t.Errorf("logs %q, want only the log record", got)
+	}
+	if got := result.String(); got != "demo created\n" {
+		t.Errorf("results %q, want the result without colors", got)
+	}
+}
@@ -0,0 +1,46 @@
+/*******************************************************************************
+ * Copyright 2017.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use this file except
+ * in compliance with the License. You may obtain a copy of the License at
+ *
+ * http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software distributed under the License
+ * is distributed on an "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express
+ * or implied. See the License for the specific language governing permissions and limitations under
+ * the License.
+ *******************************************************************************/
+
+package utility
+
+import "regexp"
+
+var (
+	// urlUserinfo matches the credentials of a URL, e.g. the token in
+	// https://oauth2:<token>@host/org/repo or https://<token>@host/org/repo.
+	urlUserinfo = regexp.MustCompile(`\b([a-zA-Z][a-zA-Z0-9+.-]*://)([^/?#@\s:]+)(:[^/?#@\s]*)?@`)
+	// urlSecretParam matches the query parameters carrying a secret.
+	urlSecretParam = regexp.MustCompile(`(?i)([?&](?:access_token|private_token|token|password|key|sig|signature)=)[^&#\s]+`)
+)
+
+// Redacted replaces the secrets removed by Redact.
+const Redacted = "***"
+
+// Redact removes the secrets of the URLs in `s`: the password of the user
+// info, the whole user info of HTTP(S) URLs since it is usually a token, and
+// the token like query parameters.
+func Redact(s string) string {
+	s = urlUserinfo.ReplaceAllStringFunc(s, func(m string) string {
+		parts := urlUserinfo.FindStringSubmatch(m)
+		scheme, user, password := parts[1], parts[2], parts[3]
+		switch {
+		case password != "":
+			return scheme + user + ":" + Redacted + "@"
+		case scheme == "http://" || scheme == "https://":
+			return scheme + Redacted + "@"
+		}
+		return m
+	})
+	return urlSecretParam.ReplaceAllString(s, "${1}"+Redacted)
+}
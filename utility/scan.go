@@ -20,14 +20,15 @@ import (
 	"os"
 )
 
+// Scan prints the prompt `info` to stderr, reads and returns user input, which stops by '\n'.
 func Scan(info ...interface{}) string {
-	fmt.Print(info...)
+	fmt.Fprint(os.Stderr, info...)
 	return readline()
 }
 
-// Scanf prints `info` to stdout with `format`, reads and returns user input, which stops by '\n'.
+// Scanf prints `info` to stderr with `format`, reads and returns user input, which stops by '\n'.
 func Scanf(format string, info ...interface{}) string {
-	fmt.Printf(format, info...)
+	fmt.Fprintf(os.Stderr, format, info...)
 	return readline()
 }
 